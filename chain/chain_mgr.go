@@ -3,7 +3,6 @@ package chain
 import (
 	"github.com/ethereum/go-ethereum/accounts"
 	"github.com/ethereum/go-ethereum/cmd/utils"
-	"github.com/ethereum/go-ethereum/consensus"
 	"github.com/ethereum/go-ethereum/consensus/tendermint/epoch"
 	"github.com/ethereum/go-ethereum/consensus/tendermint/types"
 	"github.com/ethereum/go-ethereum/core"
@@ -11,6 +10,7 @@ import (
 	"github.com/ethereum/go-ethereum/ethclient"
 	"github.com/ethereum/go-ethereum/ethdb"
 	"github.com/ethereum/go-ethereum/log"
+	ethRpc "github.com/ethereum/go-ethereum/rpc"
 	"github.com/pchain/p2p"
 	"github.com/pchain/rpc"
 	"github.com/pkg/errors"
@@ -29,15 +29,15 @@ type ChainManager struct {
 	ctx *cli.Context
 
 	mainChain     *Chain
-	mainQuit      chan int
 	mainStartDone chan struct{}
 
 	createChildChainLock sync.Mutex
 	childChains          map[string]*Chain
-	childQuits           map[string]chan int
 
 	server *p2p.PChainP2PServer
 	cch    *CrossChainHelper
+
+	transferMgr *TransferManager
 }
 
 var chainMgr *ChainManager
@@ -48,7 +48,6 @@ func GetCMInstance(ctx *cli.Context) *ChainManager {
 	once.Do(func() {
 		chainMgr = &ChainManager{ctx: ctx}
 		chainMgr.childChains = make(map[string]*Chain)
-		chainMgr.childQuits = make(map[string]chan int)
 		chainMgr.cch = &CrossChainHelper{}
 	})
 	return chainMgr
@@ -81,7 +80,16 @@ func (cm *ChainManager) LoadChains(childIds []string) error {
 	// Wait for Main Chain Start Complete
 	<-cm.mainStartDone
 
-	childChainIds := core.GetChildChainIds(cm.cch.chainInfoDB)
+	// The child chain registry is kept in the state of the main chain since the child chain state fork
+	mainChain := MustGetEthereumFromNode(cm.mainChain.EthNode).BlockChain()
+	mainBlock := mainChain.CurrentBlock()
+	mainState, err := mainChain.StateAt(mainBlock.Root())
+	if err != nil {
+		return err
+	}
+	store := cm.cch.ChainInfoStore(mainBlock.Number(), mainState)
+
+	childChainIds := core.GetChildChainIds(store)
 	log.Infof("Before Load Child Chains, childChainIds is %v, len is %d", childChainIds, len(childChainIds))
 
 	readyToLoadChains := make(map[string]bool) // Key: Child Chain ID, Value: Enable Mining
@@ -90,10 +98,12 @@ func (cm *ChainManager) LoadChains(childIds []string) error {
 	for _, chainId := range childChainIds {
 		// TODO Check Validator Address in Tendermint
 		// Check Current Validator is Child Chain Validator
-		ci := core.GetChainInfo(cm.cch.chainInfoDB, chainId)
-		// Check if we are in this child chain
+		ci := core.GetChainInfo(store, chainId)
+		// Check if we are in this child chain, the epoch is saved after the child chain sends its first block
 		if ci.Epoch != nil && cm.checkCoinbaseInChildChain(ci.Epoch) {
 			readyToLoadChains[chainId] = true
+		} else if ci.Epoch == nil && cm.checkCoinbaseInJoinedValidators(&ci.CoreChainInfo) {
+			readyToLoadChains[chainId] = true
 		}
 	}
 
@@ -135,6 +145,7 @@ func (cm *ChainManager) InitCrossChainHelper() {
 		cm.mainChain.Config.GetString("db_backend"),
 		cm.ctx.GlobalString(utils.DataDirFlag.Name))
 	cm.cch.localTX3CacheDB, _ = ethdb.NewLDBDatabase(path.Join(cm.ctx.GlobalString(utils.DataDirFlag.Name), "tx3cache"), 0, 0)
+	cm.cch.tx3Retention = cm.ctx.GlobalUint64(utils.TX3CacheRetentionFlag.Name)
 
 	chainId := MainChain
 	if cm.ctx.GlobalBool(utils.TestnetFlag.Name) {
@@ -169,7 +180,6 @@ func (cm *ChainManager) StartP2PServer() error {
 
 func (cm *ChainManager) StartMainChain() error {
 	// Start the Main Chain
-	cm.mainQuit = make(chan int)
 	cm.mainStartDone = make(chan struct{})
 
 	cm.mainChain.EthNode.SetP2PServer(cm.server.Server())
@@ -181,9 +191,6 @@ func (cm *ChainManager) StartChains() error {
 
 	for _, chain := range cm.childChains {
 		// Start each Chain
-		quit := make(chan int)
-		cm.childQuits[chain.Id] = quit
-
 		srv := cm.server.Server()
 		childProtocols := chain.EthNode.GatherProtocols()
 		// Add Child Protocols to P2P Server Protocols
@@ -245,14 +252,34 @@ func (cm *ChainManager) StartInspectEvent() {
 	}()
 }
 
+func (cm *ChainManager) StartTransferManager() error {
+
+	db, err := ethdb.NewLDBDatabase(path.Join(cm.ctx.GlobalString(utils.DataDirFlag.Name), "transfer"), 0, 0)
+	if err != nil {
+		return err
+	}
+
+	cm.transferMgr = NewTransferManager(cm, db)
+	cm.transferMgr.Start()
+	return nil
+}
+
 func (cm *ChainManager) LoadChildChainInRT(chainId string) {
 
-	// Load Child Chain data from pending data
-	cci := core.GetPendingChildChainData(cm.cch.chainInfoDB, chainId)
-	if cci == nil {
+	// Load Child Chain data from the registry of the main chain, it has been launched by the inserted block
+	mainChain := MustGetEthereumFromNode(cm.mainChain.EthNode).BlockChain()
+	mainBlock := mainChain.CurrentBlock()
+	mainState, err := mainChain.StateAt(mainBlock.Root())
+	if err != nil {
+		log.Errorf("failed to load the main chain state: %v", err)
+		return
+	}
+	ci := core.GetChainInfo(cm.cch.ChainInfoStore(mainBlock.Number(), mainState), chainId)
+	if ci == nil {
 		log.Errorf("child chain: %s does not exist, can't load", chainId)
 		return
 	}
+	cci := &ci.CoreChainInfo
 
 	validators := make([]types.GenesisValidator, 0, len(cci.JoinedValidators))
 
@@ -283,8 +310,6 @@ func (cm *ChainManager) LoadChildChainInRT(chainId string) {
 
 	if !validator {
 		log.Warnf("You are not in the validators of child chain %v, no need to start the child chain", chainId)
-		// Update Child Chain to formal
-		cm.formalizeChildChain(chainId, *cci, nil)
 		return
 	}
 
@@ -310,7 +335,7 @@ func (cm *ChainManager) LoadChildChainInRT(chainId string) {
 	privValidatorFile := cm.mainChain.Config.GetString("priv_validator_file")
 	self := types.LoadPrivValidator(privValidatorFile)
 
-	err := CreateChildChain(cm.ctx, chainId, *self, keyJson, validators)
+	err = CreateChildChain(cm.ctx, chainId, *self, keyJson, validators)
 	if err != nil {
 		log.Errorf("Create Child Chain %v failed! %v", chainId, err)
 		return
@@ -334,20 +359,11 @@ func (cm *ChainManager) LoadChildChainInRT(chainId string) {
 	chain.EthNode.SetP2PServer(srv)
 
 	// Start the new Child Chain, and it will start child chain reactors as well
-	quit := make(chan int)
-	cm.childQuits[chain.Id] = quit
-
 	err = StartChain(cm.ctx, chain, nil)
 	if err != nil {
 		return
 	}
 
-	var childEthereum *eth.Ethereum
-	chain.EthNode.Service(&childEthereum)
-	firstEpoch := childEthereum.Engine().(consensus.Tendermint).GetEpoch()
-	// Child Chain start success, then delete the pending data in chain info db
-	cm.formalizeChildChain(chainId, *cci, firstEpoch)
-
 	// Add Child Chain Id into Chain Manager
 	cm.childChains[chainId] = chain
 
@@ -358,13 +374,6 @@ func (cm *ChainManager) LoadChildChainInRT(chainId string) {
 	rpc.Hookup(chain.Id, chain.RpcHandler)
 }
 
-func (cm *ChainManager) formalizeChildChain(chainId string, cci core.CoreChainInfo, ep *epoch.Epoch) {
-	// Child Chain start success, then delete the pending data in chain info db
-	core.DeletePendingChildChainData(cm.cch.chainInfoDB, chainId)
-	// Convert the Chain Info from Pending to Formal
-	core.SaveChainInfo(cm.cch.chainInfoDB, &core.ChainInfo{CoreChainInfo: cci, Epoch: ep})
-}
-
 func (cm *ChainManager) checkCoinbaseInChildChain(childEpoch *epoch.Epoch) bool {
 	var ethereum *eth.Ethereum
 	cm.mainChain.EthNode.Service(&ethereum)
@@ -373,15 +382,68 @@ func (cm *ChainManager) checkCoinbaseInChildChain(childEpoch *epoch.Epoch) bool
 	return childEpoch.Validators.HasAddress(localEtherbase[:])
 }
 
+func (cm *ChainManager) checkCoinbaseInJoinedValidators(cci *core.CoreChainInfo) bool {
+	var ethereum *eth.Ethereum
+	cm.mainChain.EthNode.Service(&ethereum)
+	localEtherbase, _ := ethereum.Etherbase()
+
+	for _, v := range cci.JoinedValidators {
+		if v.Address == localEtherbase {
+			return true
+		}
+	}
+	return false
+}
+
+// getChildChain returns the child chain run by the node, nil if not loaded (yet)
+func (cm *ChainManager) getChildChain(chainId string) *Chain {
+	cm.createChildChainLock.Lock()
+	defer cm.createChildChainLock.Unlock()
+	return cm.childChains[chainId]
+}
+
+// AttachChain returns the in-process RPC client of the chain run by the node
+func (cm *ChainManager) AttachChain(chainId string) (*ethRpc.Client, error) {
+	chain := cm.mainChain
+	if chainId != cm.mainChain.Id {
+		chain = cm.getChildChain(chainId)
+	}
+	if chain == nil {
+		return nil, errors.Errorf("chain %s is not run by the node", chainId)
+	}
+	return chain.EthNode.Attach()
+}
+
+// WaitChainsStop blocks until the nodes of the chains are stopped, e.g. by the interrupt
 func (cm *ChainManager) WaitChainsStop() {
 
-	<-cm.mainQuit
-	for _, quit := range cm.childQuits {
-		<-quit
+	cm.mainChain.EthNode.Wait()
+
+	cm.createChildChainLock.Lock()
+	childChains := make([]*Chain, 0, len(cm.childChains))
+	for _, chain := range cm.childChains {
+		childChains = append(childChains, chain)
+	}
+	cm.createChildChainLock.Unlock()
+
+	for _, chain := range childChains {
+		chain.EthNode.Wait()
 	}
 }
 
+// Stop stops the chains run by the node, the IPC endpoints of the chains are closed and removed with their nodes
 func (cm *ChainManager) Stop() {
+	if cm.transferMgr != nil {
+		cm.transferMgr.Stop()
+	}
 	rpc.StopRPC()
+
+	cm.createChildChainLock.Lock()
+	for _, chain := range cm.childChains {
+		chain.EthNode.Stop()
+	}
+	cm.createChildChainLock.Unlock()
+	cm.mainChain.EthNode.Stop()
+
 	cm.server.Stop()
 }
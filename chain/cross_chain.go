@@ -5,7 +5,6 @@ import (
 	"errors"
 	"fmt"
 	"github.com/ethereum/go-ethereum/common"
-	"github.com/ethereum/go-ethereum/common/math"
 	"github.com/ethereum/go-ethereum/consensus"
 	"github.com/ethereum/go-ethereum/consensus/tendermint/epoch"
 	tdmTypes "github.com/ethereum/go-ethereum/consensus/tendermint/types"
@@ -17,10 +16,7 @@ import (
 	"github.com/ethereum/go-ethereum/ethdb"
 	"github.com/ethereum/go-ethereum/log"
 	"github.com/ethereum/go-ethereum/node"
-	"github.com/ethereum/go-ethereum/params"
 	"github.com/ethereum/go-ethereum/rlp"
-	"github.com/ethereum/go-ethereum/trie"
-	pabi "github.com/pchain/abi"
 	"github.com/tendermint/go-crypto"
 	dbm "github.com/tendermint/go-db"
 	"math/big"
@@ -29,14 +25,16 @@ import (
 )
 
 const (
-	OFFICIAL_MINIMUM_VALIDATORS = 1
-	OFFICIAL_MINIMUM_DEPOSIT    = "100000000000000000000000" // 100,000 * e18
+	OFFICIAL_MINIMUM_VALIDATORS = core.OFFICIAL_MINIMUM_VALIDATORS
+	OFFICIAL_MINIMUM_DEPOSIT    = core.OFFICIAL_MINIMUM_DEPOSIT
 )
 
 type CrossChainHelper struct {
 	mtx             sync.Mutex
 	chainInfoDB     dbm.DB
 	localTX3CacheDB ethdb.Database
+	//number of main chain blocks to keep a tx3 in the local cache
+	tx3Retention uint64
 	//the client does only connect to main chain
 	client *ethclient.Client
 }
@@ -53,150 +51,61 @@ func (cch *CrossChainHelper) GetClient() *ethclient.Client {
 	return cch.client
 }
 
-// CanCreateChildChain check the condition before send the create child chain into the tx pool
-func (cch *CrossChainHelper) CanCreateChildChain(from common.Address, chainId string, minValidators uint16, minDepositAmount *big.Int, startBlock, endBlock *big.Int) error {
-
-	if chainId == MainChain || chainId == TestnetChain {
-		return errors.New("you can't create PChain as a child chain, try use other name instead")
-	}
-
-	// Check if "chainId" has been created
-	ci := core.GetChainInfo(cch.chainInfoDB, chainId)
-	if ci != nil {
-		return fmt.Errorf("Chain %s has already exist, try use other name instead", chainId)
-	}
-
-	// Check if "chainId" has been registered
-	cci := core.GetPendingChildChainData(cch.chainInfoDB, chainId)
-	if cci != nil {
-		return fmt.Errorf("Chain %s has already applied, try use other name instead", chainId)
-	}
-
-	// Check the minimum validators
-	if minValidators < OFFICIAL_MINIMUM_VALIDATORS {
-		return fmt.Errorf("Validators amount is not meet the minimum official validator amount (%v)", OFFICIAL_MINIMUM_VALIDATORS)
-	}
-
-	// Check the minimum deposit amount
-	officialMinimumDeposit := math.MustParseBig256(OFFICIAL_MINIMUM_DEPOSIT)
-	if minDepositAmount.Cmp(officialMinimumDeposit) == -1 {
-		return fmt.Errorf("Deposit amount is not meet the minimum official deposit amount (%v PI)", new(big.Int).Div(officialMinimumDeposit, big.NewInt(params.PI)))
-	}
-
-	// Check start/end block
-	if startBlock.Cmp(endBlock) >= 0 {
-		return errors.New("start block number must be less than end block number")
-	}
+// ChainInfoStore returns where the block of the main chain with the number keeps the child chain registry
+func (cch *CrossChainHelper) ChainInfoStore(num *big.Int, state *state.StateDB) core.ChainInfoStore {
+	bc := MustGetEthereumFromNode(chainMgr.mainChain.EthNode).BlockChain()
+	return core.ChainInfoStoreAt(bc.Config(), num, state, cch.chainInfoDB)
+}
 
-	// Check End Block already passed
+// CanCreateChildChain check the condition before send the create child chain into the tx pool
+func (cch *CrossChainHelper) CanCreateChildChain(store core.ChainInfoStore, from common.Address, chainId string, minValidators uint16, minDepositAmount *big.Int, startBlock, endBlock *big.Int) error {
 	ethereum := MustGetEthereumFromNode(chainMgr.mainChain.EthNode)
-	currentBlock := ethereum.BlockChain().CurrentBlock()
-	if endBlock.Cmp(currentBlock.Number()) <= 0 {
-		return errors.New("end block number has already passed")
-	}
-
-	return nil
+	return core.CanCreateChildChain(store, chainId, minValidators, minDepositAmount, startBlock, endBlock, ethereum.BlockChain().CurrentBlock().Number())
 }
 
-// CreateChildChain Save the Child Chain Data into the DB, the data will be used later during Block Commit Callback
-func (cch *CrossChainHelper) CreateChildChain(from common.Address, chainId string, minValidators uint16, minDepositAmount *big.Int, startBlock, endBlock *big.Int) error {
+// CreateChildChain Save the Child Chain Data into the Store, the data will be used later during Block Finalize
+func (cch *CrossChainHelper) CreateChildChain(store core.ChainInfoStore, from common.Address, chainId string, minValidators uint16, minDepositAmount *big.Int, startBlock, endBlock *big.Int) error {
 	log.Debug("CreateChildChain - start")
 
-	cci := &core.CoreChainInfo{
-		Owner:            from,
-		ChainId:          chainId,
-		MinValidators:    minValidators,
-		MinDepositAmount: minDepositAmount,
-		StartBlock:       startBlock,
-		EndBlock:         endBlock,
-		JoinedValidators: make([]core.JoinedValidator, 0),
-	}
-	core.CreatePendingChildChainData(cch.chainInfoDB, cci)
+	core.CreateChildChain(store, from, chainId, minValidators, minDepositAmount, startBlock, endBlock)
 
 	log.Debug("CreateChildChain - end")
 	return nil
 }
 
 // ValidateJoinChildChain check the criteria whether it meets the join child chain requirement
-func (cch *CrossChainHelper) ValidateJoinChildChain(from common.Address, consensusPubkey []byte, chainId string, depositAmount *big.Int, signature []byte) error {
+func (cch *CrossChainHelper) ValidateJoinChildChain(store core.ChainInfoStore, from common.Address, consensusPubkey []byte, chainId string, depositAmount *big.Int, signature []byte) error {
 	log.Debug("ValidateJoinChildChain - start")
 
-	if chainId == MainChain || chainId == TestnetChain {
-		return errors.New("you can't join PChain as a child chain, try use other name instead")
-	}
-
-	// Check Signature of the PubKey matched against the Address
-	if err := crypto.CheckConsensusPubKey(from, consensusPubkey, signature); err != nil {
+	if err := core.ValidateJoinChildChain(store, from, consensusPubkey, chainId, depositAmount, signature); err != nil {
 		return err
 	}
 
-	// Check if "chainId" has been created/registered
-	ci := core.GetPendingChildChainData(cch.chainInfoDB, chainId)
-	if ci == nil {
-		if core.GetChainInfo(cch.chainInfoDB, chainId) != nil {
-			return fmt.Errorf("chain %s has already created/started, try use other name instead", chainId)
-		} else {
-			return fmt.Errorf("child chain %s not exist, try use other name instead", chainId)
-		}
-	}
-
-	// Check if already joined the chain
-	find := false
-	for _, joined := range ci.JoinedValidators {
-		if from == joined.Address {
-			find = true
-			break
-		}
-	}
-
-	if find {
-		return errors.New(fmt.Sprintf("You have already joined the Child Chain %s", chainId))
-	}
-
-	// Check the deposit amount
-	if !(depositAmount != nil && depositAmount.Sign() == 1) {
-		return errors.New("deposit amount must be greater than 0")
-	}
-
 	log.Debug("ValidateJoinChildChain - end")
 	return nil
 }
 
 // JoinChildChain Join the Child Chain
-func (cch *CrossChainHelper) JoinChildChain(from common.Address, pubkey crypto.PubKey, chainId string, depositAmount *big.Int) error {
+func (cch *CrossChainHelper) JoinChildChain(store core.ChainInfoStore, from common.Address, pubkey crypto.PubKey, chainId string, depositAmount *big.Int) error {
 	log.Debug("JoinChildChain - start")
 
-	// Load the Child Chain first
-	ci := core.GetPendingChildChainData(cch.chainInfoDB, chainId)
-	if ci == nil {
-		log.Errorf("JoinChildChain - Child Chain %s not exist, you can't join the chain", chainId)
-		return fmt.Errorf("Child Chain %s not exist, you can't join the chain", chainId)
-	}
-
-	for _, joined := range ci.JoinedValidators {
-		if from == joined.Address {
-			return nil
-		}
-	}
-
-	jv := core.JoinedValidator{
-		PubKey:        pubkey,
-		Address:       from,
-		DepositAmount: depositAmount,
+	if err := core.JoinChildChain(store, from, pubkey, chainId, depositAmount); err != nil {
+		log.Errorf("JoinChildChain - %v", err)
+		return err
 	}
 
-	ci.JoinedValidators = append(ci.JoinedValidators, jv)
-
-	core.UpdatePendingChildChainData(cch.chainInfoDB, ci)
-
 	log.Debug("JoinChildChain - end")
 	return nil
 }
 
+// ReadyForLaunchChildChain launches the child chains in the state since the child chain state fork. Before the
+// fork, it returns the pending index and the removed child chains to be saved by ProcessPostPendingData.
 func (cch *CrossChainHelper) ReadyForLaunchChildChain(height *big.Int, stateDB *state.StateDB) ([]string, []byte, []string) {
 	log.Debug("ReadyForLaunchChildChain - start")
 
-	readyId, updateBytes, removedId := core.GetChildChainForLaunch(cch.chainInfoDB, height, stateDB)
+	bc := MustGetEthereumFromNode(chainMgr.mainChain.EthNode).BlockChain()
+	readyId, updateBytes, removedId := core.ReadyForLaunchChildChain(bc.Config(), height, stateDB, cch.chainInfoDB)
+
 	if len(readyId) == 0 {
 		log.Debugf("ReadyForLaunchChildChain - No child chain to be launch in Block %v", height)
 	} else {
@@ -207,48 +116,8 @@ func (cch *CrossChainHelper) ReadyForLaunchChildChain(height *big.Int, stateDB *
 	return readyId, updateBytes, removedId
 }
 
-func (cch *CrossChainHelper) ProcessPostPendingData(newPendingIdxBytes []byte, deleteChildChainIds []string) {
-	core.ProcessPostPendingData(cch.chainInfoDB, newPendingIdxBytes, deleteChildChainIds)
-}
-
-func (cch *CrossChainHelper) VoteNextEpoch(ep *epoch.Epoch, from common.Address, voteHash common.Hash, txHash common.Hash) error {
-
-	voteSet := ep.GetNextEpoch().GetEpochValidatorVoteSet()
-	vote, exist := voteSet.GetVoteByAddress(from)
-
-	if exist {
-		// Overwrite the Previous Hash Vote
-		vote.VoteHash = voteHash
-		vote.TxHash = txHash
-	} else {
-		// Create a new Hash Vote
-		vote = &epoch.EpochValidatorVote{
-			Address:  from,
-			VoteHash: voteHash,
-			TxHash:   txHash,
-		}
-		voteSet.StoreVote(vote)
-	}
-	// Save the VoteSet
-	epoch.SaveEpochVoteSet(ep.GetDB(), ep.GetNextEpoch().Number, voteSet)
-	return nil
-}
-
-func (cch *CrossChainHelper) RevealVote(ep *epoch.Epoch, from common.Address, pubkey crypto.PubKey, depositAmount *big.Int, salt string, txHash common.Hash) error {
-
-	voteSet := ep.GetNextEpoch().GetEpochValidatorVoteSet()
-	vote, exist := voteSet.GetVoteByAddress(from)
-
-	if exist {
-		// Update the Hash Vote with Real Data
-		vote.PubKey = pubkey
-		vote.Amount = depositAmount
-		vote.Salt = salt
-		vote.TxHash = txHash
-	}
-	// Save the VoteSet
-	epoch.SaveEpochVoteSet(ep.GetDB(), ep.GetNextEpoch().Number, voteSet)
-	return nil
+func (cch *CrossChainHelper) ProcessPostPendingData(newPendingIdxBytes []byte, launchedChildChainIds, deleteChildChainIds []string) {
+	core.ProcessPostPendingData(core.NewChainInfoDBStore(cch.chainInfoDB), newPendingIdxBytes, launchedChildChainIds, deleteChildChainIds)
 }
 
 func (cch *CrossChainHelper) GetHeightFromMainChain() *big.Int {
@@ -275,7 +144,7 @@ func (cch *CrossChainHelper) GetEpochFromMainChain() *epoch.Epoch {
 
 // verify the signature of validators who voted for the block
 // most of the logic here is from 'VerifyHeader'
-func (cch *CrossChainHelper) VerifyChildChainProofData(bs []byte) error {
+func (cch *CrossChainHelper) VerifyChildChainProofData(store core.ChainInfoStore, bs []byte) error {
 
 	log.Debug("VerifyChildChainProofData - start")
 
@@ -326,7 +195,7 @@ func (cch *CrossChainHelper) VerifyChildChainProofData(bs []byte) error {
 		}
 	}
 
-	ci := core.GetChainInfo(cch.chainInfoDB, chainId)
+	ci := core.GetChainInfo(store, chainId)
 	if ci == nil {
 		return fmt.Errorf("chain info %s not found", chainId)
 	}
@@ -352,7 +221,7 @@ func (cch *CrossChainHelper) VerifyChildChainProofData(bs []byte) error {
 	return nil
 }
 
-func (cch *CrossChainHelper) SaveChildChainProofDataToMainChain(bs []byte) error {
+func (cch *CrossChainHelper) SaveChildChainProofDataToMainChain(store core.ChainInfoStore, bs []byte) error {
 	log.Debug("SaveChildChainProofDataToMainChain - start")
 
 	var proofData types.ChildChainProofData
@@ -376,23 +245,16 @@ func (cch *CrossChainHelper) SaveChildChainProofDataToMainChain(bs []byte) error
 	if len(tdmExtra.EpochBytes) != 0 {
 		ep := epoch.FromBytes(tdmExtra.EpochBytes)
 		if ep != nil {
-			ci := core.GetChainInfo(cch.chainInfoDB, tdmExtra.ChainID)
-			// ChainInfo is nil means we need to wait for Child Chain to be launched, this could happened during catch-up scenario
+			// The Child Chain has been launched by the main chain block before it sends any data
+			ci := core.GetChainInfo(store, tdmExtra.ChainID)
 			if ci == nil {
-				for {
-					// wait for 3 sec and try again
-					time.Sleep(3 * time.Second)
-					ci = core.GetChainInfo(cch.chainInfoDB, tdmExtra.ChainID)
-					if ci != nil {
-						break
-					}
-				}
+				return fmt.Errorf("chain info %s not found", chainId)
 			}
 
 			if ep.Number == 0 || ep.Number > ci.EpochNumber {
 				ci.EpochNumber = ep.Number
 				ci.Epoch = ep
-				core.SaveChainInfo(cch.chainInfoDB, ci)
+				core.SaveChainInfo(store, ci)
 				log.Infof("Epoch saved from chain: %s, epoch: %v", chainId, ep)
 			}
 		}
@@ -405,148 +267,37 @@ func (cch *CrossChainHelper) SaveChildChainProofDataToMainChain(bs []byte) error
 func (cch *CrossChainHelper) ValidateTX3ProofData(proofData *types.TX3ProofData) error {
 	log.Debug("ValidateTX3ProofData - start")
 
-	header := proofData.Header
-	// Don't waste time checking blocks from the future
-	if header.Time.Cmp(big.NewInt(time.Now().Unix())) > 0 {
-		return errors.New("block in the future")
-	}
-
-	tdmExtra, err := tdmTypes.ExtractTendermintExtra(header)
+	// The chain info is read from the current state of the main chain
+	bc := MustGetEthereumFromNode(chainMgr.mainChain.EthNode).BlockChain()
+	current := bc.CurrentBlock()
+	state, err := bc.StateAt(current.Root())
 	if err != nil {
 		return err
 	}
-
-	chainId := tdmExtra.ChainID
-	if chainId == "" || chainId == MainChain || chainId == TestnetChain {
-		return fmt.Errorf("invalid child chain id: %s", chainId)
-	}
-
-	if header.Nonce != (types.TendermintEmptyNonce) && !bytes.Equal(header.Nonce[:], types.TendermintNonce) {
-		return errors.New("invalid nonce")
-	}
-
-	if header.MixDigest != types.TendermintDigest {
-		return errors.New("invalid mix digest")
-	}
-
-	if header.UncleHash != types.TendermintNilUncleHash {
-		return errors.New("invalid uncle Hash")
-	}
-
-	if header.Difficulty == nil || header.Difficulty.Cmp(types.TendermintDefaultDifficulty) != 0 {
-		return errors.New("invalid difficulty")
-	}
-
-	// special case: epoch 0 update
-	// TODO: how to verify this block which includes epoch 0?
-	if tdmExtra.EpochBytes != nil && len(tdmExtra.EpochBytes) != 0 {
-		ep := epoch.FromBytes(tdmExtra.EpochBytes)
-		if ep != nil && ep.Number == 0 {
-			return nil
-		}
-	}
-
-	ci := core.GetChainInfo(cch.chainInfoDB, chainId)
-	if ci == nil {
-		return fmt.Errorf("chain info %s not found", chainId)
-	}
-	epoch := ci.GetEpochByBlockNumber(tdmExtra.Height)
-	if epoch == nil {
-		return fmt.Errorf("could not get epoch for block height %v", tdmExtra.Height)
-	}
-	valSet := epoch.Validators
-	if !bytes.Equal(valSet.Hash(), tdmExtra.ValidatorsHash) {
-		return errors.New("inconsistent validator set")
-	}
-
-	seenCommit := tdmExtra.SeenCommit
-	if !bytes.Equal(tdmExtra.SeenCommitHash, seenCommit.Hash()) {
-		return errors.New("invalid committed seals")
-	}
-
-	if err = valSet.VerifyCommit(tdmExtra.ChainID, tdmExtra.Height, seenCommit); err != nil {
+	if err := core.VerifyTX3ProofData(cch.ChainInfoStore(current.Number(), state), proofData); err != nil {
 		return err
 	}
 
-	// tx merkle proof verify
-	keybuf := new(bytes.Buffer)
-	for i, txIndex := range proofData.TxIndexs {
-		keybuf.Reset()
-		rlp.Encode(keybuf, uint(txIndex))
-		_, err, _ := trie.VerifyProof(header.TxHash, keybuf.Bytes(), proofData.TxProofs[i])
-		if err != nil {
-			return err
-		}
-	}
-
 	log.Debug("ValidateTX3ProofData - end")
 	return nil
 }
 
 func (cch *CrossChainHelper) ValidateTX4WithInMemTX3ProofData(tx4 *types.Transaction, tx3ProofData *types.TX3ProofData) error {
-	// TX4
-	signer := types.NewEIP155Signer(tx4.ChainId())
-	from, err := types.Sender(signer, tx4)
-	if err != nil {
-		return core.ErrInvalidSender
-	}
-
-	var args pabi.WithdrawFromMainChainArgs
-
-	if !pabi.IsPChainContractAddr(tx4.To()) {
-		return errors.New("invalid TX4: wrong To()")
-	}
-
-	data := tx4.Data()
-	function, err := pabi.FunctionTypeFromId(data[:4])
-	if err != nil {
-		return err
-	}
-
-	if function != pabi.WithdrawFromMainChain {
-		return errors.New("invalid TX4: wrong function")
-	}
-
-	if err := pabi.ChainABI.UnpackMethodInputs(&args, pabi.WithdrawFromMainChain.String(), data[4:]); err != nil {
-		return err
-	}
-
-	// TX3
-	header := tx3ProofData.Header
-	if err != nil {
-		return err
-	}
-	keybuf := new(bytes.Buffer)
-	rlp.Encode(keybuf, tx3ProofData.TxIndexs[0])
-	val, err, _ := trie.VerifyProof(header.TxHash, keybuf.Bytes(), tx3ProofData.TxProofs[0])
-	if err != nil {
-		return err
-	}
-
-	var tx3 types.Transaction
-	err = rlp.DecodeBytes(val, &tx3)
-	if err != nil {
-		return err
-	}
-
-	signer2 := types.NewEIP155Signer(tx3.ChainId())
-	tx3From, err := types.Sender(signer2, &tx3)
-	if err != nil {
-		return core.ErrInvalidSender
-	}
+	return core.ValidateTX4WithTX3ProofData(tx4, tx3ProofData)
+}
 
-	var tx3Args pabi.WithdrawFromChildChainArgs
-	tx3Data := tx3.Data()
-	if err := pabi.ChainABI.UnpackMethodInputs(&tx3Args, pabi.WithdrawFromChildChain.String(), tx3Data[4:]); err != nil {
-		return err
+func (cch *CrossChainHelper) Transfer(from common.Address, fromChainId, toChainId string, amount, gasPrice *big.Int) (common.Hash, error) {
+	if chainMgr.transferMgr == nil {
+		return common.Hash{}, errors.New("transfer manager not started yet")
 	}
+	return chainMgr.transferMgr.Transfer(from, fromChainId, toChainId, amount, gasPrice)
+}
 
-	// Does TX3 & TX4 Match
-	if from != tx3From || args.ChainId != tx3Args.ChainId || args.Amount.Cmp(tx3.Value()) != 0 {
-		return errors.New("params are not consistent with tx in child chain")
+func (cch *CrossChainHelper) GetTransfer(id common.Hash) *core.CrossChainTransfer {
+	if chainMgr.transferMgr == nil {
+		return nil
 	}
-
-	return nil
+	return chainMgr.transferMgr.GetTransfer(id)
 }
 
 // TX3LocalCache start
@@ -559,7 +310,7 @@ func (cch *CrossChainHelper) DeleteTX3(chainId string, txHash common.Hash) {
 }
 
 func (cch *CrossChainHelper) WriteTX3ProofData(proofData *types.TX3ProofData) error {
-	return core.WriteTX3ProofData(cch.localTX3CacheDB, proofData)
+	return core.WriteTX3ProofData(cch.localTX3CacheDB, proofData, cch.GetHeightFromMainChain().Uint64())
 }
 
 func (cch *CrossChainHelper) GetTX3ProofData(chainId string, txHash common.Hash) *types.TX3ProofData {
@@ -570,6 +321,21 @@ func (cch *CrossChainHelper) GetAllTX3ProofData() []*types.TX3ProofData {
 	return core.GetAllTX3ProofData(cch.localTX3CacheDB)
 }
 
+func (cch *CrossChainHelper) ListPendingTX3(from common.Address) []*core.TX3IndexEntry {
+	return core.GetTX3IndexEntriesBySender(cch.localTX3CacheDB, from)
+}
+
+// PruneTX3 removes the tx3s which have been kept in the cache longer than the retention
+func (cch *CrossChainHelper) PruneTX3(mainHeight uint64) {
+	if cch.localTX3CacheDB == nil || cch.tx3Retention == 0 || mainHeight <= cch.tx3Retention {
+		return
+	}
+
+	if pruned := core.PruneTX3(cch.localTX3CacheDB, mainHeight-cch.tx3Retention); pruned > 0 {
+		log.Infof("PruneTX3 - %v expired tx3(s) removed at main chain height %v", pruned, mainHeight)
+	}
+}
+
 // TX3LocalCache end
 
 func MustGetEthereumFromNode(node *node.Node) *eth.Ethereum {
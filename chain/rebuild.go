@@ -0,0 +1,281 @@
+package chain
+
+import (
+	"errors"
+	"fmt"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/tendermint/epoch"
+	tdmTypes "github.com/ethereum/go-ethereum/consensus/tendermint/types"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rlp"
+	pabi "github.com/pchain/abi"
+	"github.com/tendermint/go-crypto"
+	dbm "github.com/tendermint/go-db"
+	"math/big"
+	"sort"
+)
+
+// The epoch db, the chain info db and the tx3 cache are only written as side effects of the inserted blocks:
+// the epochs carried by the blocks, the pending ops of the child chain txs before the child chain state fork and
+// the tx3 proofs broadcast by the child chains. The functions below rebuild them from the blocks stored in the
+// chain db, the target dbs must be empty. The blocks restored from a snapshot are not in the chain db, so such
+// a chain can't be rebuilt.
+
+// RebuildEpochs replays the epochs carried by the blocks from 1 to head into the epoch db, it returns the
+// epoch of the head block
+func RebuildEpochs(chainDb ethdb.Database, epochDB dbm.DB, genDoc *tdmTypes.GenesisDoc, head uint64) (*epoch.Epoch, error) {
+	headHeader := core.GetHeader(chainDb, core.GetCanonicalHash(chainDb, head), head)
+	if headHeader == nil {
+		return nil, fmt.Errorf("block %v not found", head)
+	}
+	// the passed proposals of all the epochs are kept in the state
+	headState, err := state.New(headHeader.Root, state.NewDatabase(chainDb))
+	if err != nil {
+		return nil, fmt.Errorf("state of the head block %v not found: %v", head, err)
+	}
+
+	replayer := epoch.NewReplayer(epochDB, genDoc, headState)
+	for number := uint64(1); number <= head; number++ {
+		header := core.GetHeader(chainDb, core.GetCanonicalHash(chainDb, number), number)
+		if header == nil {
+			return nil, fmt.Errorf("block %v not found", number)
+		}
+		tdmExtra, err := tdmTypes.ExtractTendermintExtra(header)
+		if err != nil {
+			return nil, fmt.Errorf("block %v: %v", number, err)
+		}
+		if err := replayer.ReplayBlock(number, tdmExtra.EpochBytes); err != nil {
+			return nil, err
+		}
+	}
+
+	// the validators of the next epoch are entered after the end block, but only known from the next block
+	current := replayer.Current()
+	if head > 0 && head == current.EndBlock {
+		return nil, fmt.Errorf("head block %v is the end block of epoch %v, import the next block before the rebuild", head, current.Number)
+	}
+	return current, nil
+}
+
+// RebuildChainInfo replays the child chain txs of the main chain blocks before the child chain state fork into
+// the chain info db, the same way the pending ops are applied after the blocks are inserted. The chain info db
+// is imported to the state by the fork block, and not written after it.
+func RebuildChainInfo(mainDb ethdb.Database, config *params.ChainConfig, chainInfoDB dbm.DB, head uint64) error {
+	cch := &CrossChainHelper{chainInfoDB: chainInfoDB}
+	store := core.NewChainInfoDBStore(chainInfoDB)
+
+	// launching the child chains moves the deposits in the state, which is already done by the blocks
+	memDb, _ := ethdb.NewMemDatabase()
+	scratch, _ := state.New(common.Hash{}, state.NewDatabase(memDb))
+
+	for number := uint64(1); number <= head && !config.IsChildChainState(new(big.Int).SetUint64(number)); number++ {
+		block, receipts, err := canonicalBlockWithReceipts(mainDb, number)
+		if err != nil {
+			return err
+		}
+
+		// the child chains are checked for launch in Finalize, before the ops of the block are applied
+		launched, newPendingIdx, deleted := core.GetChildChainForLaunch(store, block.Number(), scratch)
+
+		for i, tx := range block.Transactions() {
+			if !pabi.IsPChainContractAddr(tx.To()) || len(tx.Data()) < 4 || receipts[i].Status == types.ReceiptStatusFailed {
+				continue
+			}
+			if err := replayChildChainTx(cch, store, tx); err != nil {
+				return fmt.Errorf("failed to replay tx %x in block %v: %v", tx.Hash(), number, err)
+			}
+		}
+
+		if newPendingIdx != nil || len(deleted) > 0 {
+			core.ProcessPostPendingData(store, newPendingIdx, launched, deleted)
+		}
+	}
+	return nil
+}
+
+// replayChildChainTx applies the pending op of the child chain tx to the chain info db
+func replayChildChainTx(cch *CrossChainHelper, store core.ChainInfoStore, tx *types.Transaction) error {
+	data := tx.Data()
+	function, err := pabi.FunctionTypeFromId(data[:4])
+	if err != nil {
+		return err
+	}
+
+	switch function {
+	case pabi.CreateChildChain:
+		from, err := types.Sender(types.NewEIP155Signer(tx.ChainId()), tx)
+		if err != nil {
+			return err
+		}
+		var args pabi.CreateChildChainArgs
+		if err := pabi.ChainABI.UnpackMethodInputs(&args, pabi.CreateChildChain.String(), data[4:]); err != nil {
+			return err
+		}
+		return cch.CreateChildChain(store, from, args.ChainId, args.MinValidators, args.MinDepositAmount, args.StartBlock, args.EndBlock)
+
+	case pabi.JoinChildChain:
+		from, err := types.Sender(types.NewEIP155Signer(tx.ChainId()), tx)
+		if err != nil {
+			return err
+		}
+		var args pabi.JoinChildChainArgs
+		if err := pabi.ChainABI.UnpackMethodInputs(&args, pabi.JoinChildChain.String(), data[4:]); err != nil {
+			return err
+		}
+		var pub crypto.BLSPubKey
+		copy(pub[:], args.PubKey)
+		return cch.JoinChildChain(store, from, pub, args.ChainId, tx.Value())
+
+	case pabi.SaveDataToMainChain:
+		var bs []byte
+		if err := pabi.ChainABI.UnpackMethodInputs(&bs, pabi.SaveDataToMainChain.String(), data[4:]); err != nil {
+			return err
+		}
+		// the op of the data sent by the child chain not launched fails, the same as the node
+		var proofData types.ChildChainProofData
+		if err := rlp.DecodeBytes(bs, &proofData); err != nil {
+			return err
+		}
+		tdmExtra, err := tdmTypes.ExtractTendermintExtra(proofData.Header)
+		if err != nil {
+			return err
+		}
+		if core.GetChainInfo(store, tdmExtra.ChainID) == nil {
+			return nil
+		}
+		return cch.SaveChildChainProofDataToMainChain(store, bs)
+	}
+	return nil
+}
+
+// RebuildTX3Cache writes the tx3s of the child chain blocks into the tx3 cache, except the ones withdrawn by the
+// tx4s of the main chain blocks from 1 to head, and the ones expired by the retention (0 keeps all). A tx3 is
+// taken as received at the last main chain block not later than its child chain block.
+func RebuildTX3Cache(mainDb ethdb.Database, head uint64, childDbs map[string]ethdb.Database, tx3CacheDb ethdb.Database, retention uint64) error {
+	type tx3Key struct {
+		chainId string
+		txHash  common.Hash
+	}
+
+	// the tx3s are deleted from the cache by the tx4s in the inserted blocks, the same as pruneTX3Cache
+	withdrawn := make(map[tx3Key]bool)
+	for number := uint64(1); number <= head; number++ {
+		block, err := canonicalBlock(mainDb, number)
+		if err != nil {
+			return err
+		}
+		for _, tx := range block.Transactions() {
+			data := tx.Data()
+			if !pabi.IsPChainContractAddr(tx.To()) || len(data) < 4 {
+				continue
+			}
+			if function, err := pabi.FunctionTypeFromId(data[:4]); err != nil || function != pabi.WithdrawFromMainChain {
+				continue
+			}
+			var args pabi.WithdrawFromMainChainArgs
+			if err := pabi.ChainABI.UnpackMethodInputs(&args, pabi.WithdrawFromMainChain.String(), data[4:]); err != nil {
+				continue
+			}
+			withdrawn[tx3Key{args.ChainId, args.TxHash}] = true
+		}
+	}
+
+	var expireBefore uint64
+	if retention > 0 && head > retention {
+		expireBefore = head - retention
+	}
+
+	for chainId, childDb := range childDbs {
+		childHead, err := HeadNumber(childDb)
+		if err != nil {
+			return fmt.Errorf("chain %s: %v", chainId, err)
+		}
+		for number := uint64(1); number <= childHead; number++ {
+			block := core.GetBlock(childDb, core.GetCanonicalHash(childDb, number), number)
+			if block == nil {
+				return fmt.Errorf("block %v of chain %s not found", number, chainId)
+			}
+
+			receipts := core.GetBlockReceipts(childDb, block.Hash(), number)
+			proofData, err := types.NewTX3ProofData(block, receipts)
+			if err != nil {
+				return fmt.Errorf("block %v of chain %s: %v", number, chainId, err)
+			}
+			if len(proofData.TxIndexs) == 0 && len(proofData.ReceiptProofs) == 0 {
+				continue
+			}
+
+			mainHeight := receivedHeight(mainDb, head, block.Time())
+			if mainHeight < expireBefore {
+				continue
+			}
+			if err := core.WriteTX3ProofData(tx3CacheDb, proofData, mainHeight); err != nil {
+				return fmt.Errorf("block %v of chain %s: %v", number, chainId, err)
+			}
+
+			var txHashes []common.Hash
+			for _, txIndex := range proofData.TxIndexs {
+				txHashes = append(txHashes, block.Transactions()[txIndex].Hash())
+			}
+			for _, receiptProof := range proofData.ReceiptProofs {
+				for _, tx := range types.ContractTX3s(receipts[receiptProof.Index]) {
+					txHashes = append(txHashes, tx.Hash())
+				}
+			}
+			for _, txHash := range txHashes {
+				if withdrawn[tx3Key{chainId, txHash}] {
+					core.DeleteTX3(tx3CacheDb, chainId, txHash)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// receivedHeight returns the last main chain block not later than the given time
+func receivedHeight(mainDb ethdb.Database, head uint64, time *big.Int) uint64 {
+	n := sort.Search(int(head)+1, func(i int) bool {
+		header := core.GetHeader(mainDb, core.GetCanonicalHash(mainDb, uint64(i)), uint64(i))
+		return header == nil || header.Time.Cmp(time) > 0
+	})
+	if n == 0 {
+		return 0
+	}
+	return uint64(n - 1)
+}
+
+// HeadNumber returns the number of the head block in the chain db
+func HeadNumber(db ethdb.Database) (uint64, error) {
+	hash := core.GetHeadBlockHash(db)
+	number := core.GetBlockNumber(db, hash)
+	if core.GetHeader(db, hash, number) == nil {
+		return 0, errors.New("head block not found")
+	}
+	return number, nil
+}
+
+// canonicalBlock returns the canonical block with the given number
+func canonicalBlock(db ethdb.Database, number uint64) (*types.Block, error) {
+	block := core.GetBlock(db, core.GetCanonicalHash(db, number), number)
+	if block == nil {
+		return nil, fmt.Errorf("block %v not found", number)
+	}
+	return block, nil
+}
+
+// canonicalBlockWithReceipts returns the canonical block with the given number and its receipts
+func canonicalBlockWithReceipts(db ethdb.Database, number uint64) (*types.Block, types.Receipts, error) {
+	block, err := canonicalBlock(db, number)
+	if err != nil {
+		return nil, nil, err
+	}
+	receipts := core.GetBlockReceipts(db, block.Hash(), number)
+	if len(receipts) != len(block.Transactions()) {
+		return nil, nil, fmt.Errorf("receipts of block %v not found", number)
+	}
+	return block, receipts, nil
+}
@@ -0,0 +1,325 @@
+package chain
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rlp"
+	"math/big"
+	"sync"
+	"time"
+)
+
+const (
+	transferCheckInterval = 3 * time.Second
+	transferCallTimeout   = 30 * time.Second
+)
+
+var transferPrefix = []byte("transfer-") // transferPrefix + first tx hash -> transfer
+
+// TransferManager drives the two-step cross chain transfers (TX1 + TX2 for deposit,
+// TX3 + TX4 for withdraw) on behalf of the user, the transfers are persisted so they
+// can be resumed after restart.
+type TransferManager struct {
+	cm *ChainManager
+	db ethdb.Database
+
+	mtx       sync.Mutex
+	transfers map[common.Hash]*core.CrossChainTransfer // unfinished transfers
+
+	quit chan struct{}
+	wg   sync.WaitGroup
+}
+
+func NewTransferManager(cm *ChainManager, db ethdb.Database) *TransferManager {
+	return &TransferManager{
+		cm:        cm,
+		db:        db,
+		transfers: make(map[common.Hash]*core.CrossChainTransfer),
+		quit:      make(chan struct{}),
+	}
+}
+
+// Start loads the unfinished transfers from db and starts to drive them
+func (tm *TransferManager) Start() {
+	err := ethdb.IteratePrefix(tm.db, transferPrefix, func(key, value []byte) bool {
+		t := new(core.CrossChainTransfer)
+		if err := rlp.DecodeBytes(value, t); err != nil {
+			log.Errorf("TransferManager - failed to decode transfer %x, err: %v", key, err)
+			return true
+		}
+		if !t.Finished() {
+			tm.transfers[t.Id()] = t
+		}
+		return true
+	})
+	if err != nil {
+		log.Errorf("TransferManager - failed to load the transfers, err: %v", err)
+	}
+	log.Infof("TransferManager - %v unfinished transfer(s) loaded", len(tm.transfers))
+
+	tm.wg.Add(1)
+	go tm.loop()
+}
+
+func (tm *TransferManager) Stop() {
+	close(tm.quit)
+	tm.wg.Wait()
+	tm.db.Close()
+}
+
+// Transfer sends the first tx of the transfer, and returns the transfer id (the hash of the first tx)
+func (tm *TransferManager) Transfer(from common.Address, fromChainId, toChainId string, amount, gasPrice *big.Int) (common.Hash, error) {
+
+	if amount == nil || amount.Sign() <= 0 {
+		return common.Hash{}, errors.New("amount must be greater than 0")
+	}
+
+	mainChainId := tm.cm.mainChain.Id
+	t := &core.CrossChainTransfer{
+		From:     from,
+		Amount:   amount,
+		GasPrice: gasPrice,
+	}
+	if fromChainId == mainChainId && toChainId != mainChainId {
+		t.Type = core.DepositTransfer
+		t.ChainId = toChainId
+	} else if fromChainId != mainChainId && toChainId == mainChainId {
+		t.Type = core.WithdrawTransfer
+		t.ChainId = fromChainId
+	} else {
+		return common.Hash{}, fmt.Errorf("one and only one side of the transfer must be the main chain %s", mainChainId)
+	}
+
+	childChain := tm.cm.getChildChain(t.ChainId)
+	if childChain == nil {
+		return common.Hash{}, fmt.Errorf("child chain %s is not running on this node", t.ChainId)
+	}
+
+	var hash common.Hash
+	var err error
+	if t.Type == core.DepositTransfer {
+		hash, err = callChain(tm.cm.mainChain, "chain_depositInMainChain", from, t.ChainId, (*hexutil.Big)(amount), (*hexutil.Big)(gasPrice))
+	} else {
+		hash, err = callChain(childChain, "chain_withdrawFromChildChain", from, (*hexutil.Big)(amount), (*hexutil.Big)(gasPrice))
+	}
+	if err != nil {
+		return common.Hash{}, err
+	}
+
+	t.FirstTx = hash
+	t.Status = core.TransferFirstTxSent
+	if err := tm.save(t); err != nil {
+		return common.Hash{}, err
+	}
+
+	tm.mtx.Lock()
+	tm.transfers[t.Id()] = t
+	tm.mtx.Unlock()
+
+	log.Infof("TransferManager - %v transfer %x of chain %s started", t.Type, t.Id(), t.ChainId)
+	return t.Id(), nil
+}
+
+// GetTransfer returns the transfer with the id, nil if not found
+func (tm *TransferManager) GetTransfer(id common.Hash) *core.CrossChainTransfer {
+	tm.mtx.Lock()
+	defer tm.mtx.Unlock()
+
+	if t, ok := tm.transfers[id]; ok {
+		cpy := *t
+		return &cpy
+	}
+
+	bs, err := tm.db.Get(append(transferPrefix, id.Bytes()...))
+	if len(bs) == 0 || err != nil {
+		return nil
+	}
+	t := new(core.CrossChainTransfer)
+	if err := rlp.DecodeBytes(bs, t); err != nil {
+		return nil
+	}
+	return t
+}
+
+func (tm *TransferManager) loop() {
+	defer tm.wg.Done()
+
+	ticker := time.NewTicker(transferCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			// The transfers are advanced on the copies without the lock, the calls to the chains could take
+			// long, Transfer and GetTransfer are not blocked by them
+			tm.mtx.Lock()
+			transfers := make([]*core.CrossChainTransfer, 0, len(tm.transfers))
+			for _, t := range tm.transfers {
+				cpy := *t
+				transfers = append(transfers, &cpy)
+			}
+			tm.mtx.Unlock()
+
+			for _, t := range transfers {
+				if !tm.advance(t) {
+					continue
+				}
+				tm.mtx.Lock()
+				id := t.Id()
+				if err := tm.save(t); err != nil {
+					log.Errorf("TransferManager - failed to save transfer %x, err: %v", id, err)
+				}
+				if t.Finished() {
+					log.Infof("TransferManager - %v transfer %x of chain %s finished with status %v", t.Type, id, t.ChainId, t.Status)
+					delete(tm.transfers, id)
+				} else {
+					tm.transfers[id] = t
+				}
+				tm.mtx.Unlock()
+			}
+		case <-tm.quit:
+			return
+		}
+	}
+}
+
+// advance moves the transfer forward as far as possible, it returns true if the transfer has been changed
+func (tm *TransferManager) advance(t *core.CrossChainTransfer) bool {
+
+	childChain := tm.cm.getChildChain(t.ChainId)
+	if childChain == nil {
+		// child chain not loaded (yet), try later
+		return false
+	}
+
+	firstChain, secondChain := tm.cm.mainChain, childChain
+	if t.Type == core.WithdrawTransfer {
+		firstChain, secondChain = childChain, tm.cm.mainChain
+	}
+
+	changed := false
+	for {
+		switch t.Status {
+		case core.TransferFirstTxSent:
+			mined, known, err := txStatus(firstChain, t.FirstTx)
+			if !known {
+				t.Status = core.TransferFailed
+				t.Error = fmt.Sprintf("tx %x has been dropped", t.FirstTx)
+				return true
+			}
+			if !mined {
+				return changed
+			}
+			if err != nil {
+				t.Status = core.TransferFailed
+				t.Error = err.Error()
+				return true
+			}
+			t.Status = core.TransferFirstTxMined
+			changed = true
+
+		case core.TransferFirstTxMined:
+			var hash common.Hash
+			var err error
+			if t.Type == core.DepositTransfer {
+				hash, err = callChain(secondChain, "chain_depositInChildChain", t.From, t.FirstTx)
+			} else {
+				// wait for the tx3 proof data to be received by the main chain
+				if tm.cm.cch.GetTX3(t.ChainId, t.FirstTx) == nil {
+					return changed
+				}
+				hash, err = callChain(secondChain, "chain_withdrawFromMainChain", t.From, (*hexutil.Big)(t.Amount), t.ChainId, t.FirstTx)
+			}
+			if err != nil {
+				// try again later, the error is kept for the status query
+				if t.Error != err.Error() {
+					t.Error = err.Error()
+					changed = true
+				}
+				return changed
+			}
+			t.SecondTx = hash
+			t.Status = core.TransferSecondTxSent
+			t.Error = ""
+			changed = true
+
+		case core.TransferSecondTxSent:
+			mined, known, err := txStatus(secondChain, t.SecondTx)
+			if !known {
+				// the second tx has been dropped, send it again
+				t.Status = core.TransferFirstTxMined
+				t.Error = fmt.Sprintf("tx %x has been dropped", t.SecondTx)
+				return true
+			}
+			if !mined {
+				return changed
+			}
+			if err != nil {
+				t.Status = core.TransferFailed
+				t.Error = err.Error()
+				return true
+			}
+			t.Status = core.TransferCompleted
+			return true
+
+		default:
+			return changed
+		}
+	}
+}
+
+func (tm *TransferManager) save(t *core.CrossChainTransfer) error {
+	bs, err := rlp.EncodeToBytes(t)
+	if err != nil {
+		return err
+	}
+	return tm.db.Put(append(transferPrefix, t.Id().Bytes()...), bs)
+}
+
+// txStatus returns whether the tx has been mined, whether the tx is known (mined or in the tx pool), and the
+// error if the mined tx failed
+func txStatus(chain *Chain, hash common.Hash) (mined bool, known bool, err error) {
+	ethereum := MustGetEthereumFromNode(chain.EthNode)
+	if receipt, blockHash, number, _ := core.GetReceipt(ethereum.ChainDb(), hash); blockHash != (common.Hash{}) {
+		return true, true, receiptError(ethereum.BlockChain().Config(), number, hash, receipt)
+	}
+	return false, ethereum.TxPool().Get(hash) != nil, nil
+}
+
+// receiptError returns the error if the receipt of the tx mined in the block is failed. The failed built-in
+// functions were not mined before the built-in revert fork, and the receipts of the mined ones were always failed.
+func receiptError(config *params.ChainConfig, number uint64, hash common.Hash, receipt *types.Receipt) error {
+	if !config.IsBuiltinRevert(new(big.Int).SetUint64(number)) || receipt.Status != types.ReceiptStatusFailed {
+		return nil
+	}
+	if receipt.Reason != "" {
+		return fmt.Errorf("tx %x failed: %s", hash, receipt.Reason)
+	}
+	return fmt.Errorf("tx %x failed", hash)
+}
+
+// callChain calls the rpc method of the chain through the in-process rpc client, and returns the tx hash
+func callChain(chain *Chain, method string, args ...interface{}) (common.Hash, error) {
+	client, err := chain.EthNode.Attach()
+	if err != nil {
+		return common.Hash{}, err
+	}
+	defer client.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), transferCallTimeout)
+	defer cancel()
+
+	var hash common.Hash
+	if err := client.CallContext(ctx, &hash, method, args...); err != nil {
+		return common.Hash{}, err
+	}
+	return hash, nil
+}
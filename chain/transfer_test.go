@@ -0,0 +1,58 @@
+package chain
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// Tests the failed receipts fail the transfer since the built-in revert fork, the mined txs succeeded before.
+func TestTransferReceiptError(t *testing.T) {
+	config := params.NewChildChainConfig("child_0")
+	config.BuiltinRevertBlock = big.NewInt(10)
+	hash := common.HexToHash("0x1")
+
+	failed := &types.Receipt{Status: types.ReceiptStatusFailed, Reason: "no enough balance to withdraw"}
+	if err := receiptError(config, 9, hash, failed); err != nil {
+		t.Errorf("mined tx failed before the fork: %v", err)
+	}
+	if err := receiptError(config, 10, hash, failed); err == nil {
+		t.Errorf("failed receipt accepted since the fork")
+	}
+	succeeded := &types.Receipt{Status: types.ReceiptStatusSuccessful}
+	if err := receiptError(config, 10, hash, succeeded); err != nil {
+		t.Errorf("successful receipt failed since the fork: %v", err)
+	}
+}
+
+// Tests the unfinished transfers are loaded from the database by Start.
+func TestTransferManagerStart(t *testing.T) {
+	db, _ := ethdb.NewMemDatabase()
+	cm := &ChainManager{childChains: make(map[string]*Chain)}
+	tm := NewTransferManager(cm, db)
+
+	unfinished := &core.CrossChainTransfer{ChainId: "child_0", Amount: big.NewInt(1), GasPrice: big.NewInt(1),
+		FirstTx: common.HexToHash("0x1"), Status: core.TransferFirstTxSent}
+	finished := &core.CrossChainTransfer{ChainId: "child_0", Amount: big.NewInt(1), GasPrice: big.NewInt(1),
+		FirstTx: common.HexToHash("0x2"), Status: core.TransferCompleted}
+	for _, tr := range []*core.CrossChainTransfer{unfinished, finished} {
+		if err := tm.save(tr); err != nil {
+			t.Fatalf("failed to save the transfer: %v", err)
+		}
+	}
+	db.Put([]byte("transfer"), []byte{1})
+
+	tm.Start()
+	defer tm.Stop()
+
+	tm.mtx.Lock()
+	defer tm.mtx.Unlock()
+	if len(tm.transfers) != 1 || tm.transfers[unfinished.Id()] == nil {
+		t.Fatalf("unexpected transfers loaded: %v", tm.transfers)
+	}
+}
@@ -0,0 +1,400 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"github.com/ethereum/go-ethereum/cmd/utils"
+	"github.com/ethereum/go-ethereum/common"
+	tdmTypes "github.com/ethereum/go-ethereum/consensus/tendermint/types"
+	"github.com/ethereum/go-ethereum/console"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/pchain/chain"
+	"github.com/pchain/pchainclient"
+	"github.com/tendermint/go-crypto"
+	"gopkg.in/urfave/cli.v1"
+	"math/big"
+	"os"
+	"strconv"
+)
+
+var (
+	ChildPrivValidatorFlag = cli.StringFlag{
+		Name:  "privvalidator",
+		Usage: "priv_validator file of the consensus key joining the child chain (default: the one of the main chain in the datadir)",
+	}
+	ChildYesFlag = cli.BoolFlag{
+		Name:  "yes",
+		Usage: "Send the tx without the confirmation",
+	}
+
+	childCommand = cli.Command{
+		Name:     "child",
+		Usage:    "Create, join and check the child chains",
+		Category: "ACCOUNT COMMANDS",
+		Description: `
+
+Create a child chain or join the pending child chain as validator on the main chain. The
+tx is dry run by the validate callback of the node against the pending state of the main
+chain when it is built, and the balance of the sender is checked against its value and gas,
+so the tx which would be rejected is never sent. The tx is signed offline with a keystore file
+or USB hardware wallet, the same as "pchain tx".
+
+    pchain child create --from <address> --keyfile <file> | --usb <chainId> <minValidators> <minDepositAmount> <startBlock> <endBlock>
+    pchain child join --keyfile <file> | --usb <chainId> <depositAmount>
+    pchain child status <chainId>`,
+		Subcommands: []cli.Command{
+			{
+				Name:      "create",
+				Usage:     "Create a child chain waiting for the validators to join",
+				ArgsUsage: "<chainId> <minValidators> <minDepositAmount> <startBlock> <endBlock>",
+				Action:    utils.MigrateFlags(childCreate),
+				Flags: []cli.Flag{
+					TxNodeFlag,
+					TxFromFlag,
+					TxKeyFileFlag,
+					TxUSBFlag,
+					TxHDPathFlag,
+					utils.PasswordFileFlag,
+					ChildYesFlag,
+				},
+				Description: `
+    pchain child create --from <address> --keyfile <file> | --usb <chainId> <minValidators> <minDepositAmount> <startBlock> <endBlock>
+
+The child chain is launched at the first block from the start block on where the joined
+validators and their total deposit (in wei) meet the minimum, it is removed if not launched
+by the end block.`,
+			},
+			{
+				Name:      "join",
+				Usage:     "Join the pending child chain as validator with the local consensus key",
+				ArgsUsage: "<chainId> <depositAmount>",
+				Action:    utils.MigrateFlags(childJoin),
+				Flags: []cli.Flag{
+					TxNodeFlag,
+					TxFromFlag,
+					TxKeyFileFlag,
+					TxUSBFlag,
+					TxHDPathFlag,
+					utils.PasswordFileFlag,
+					ChildPrivValidatorFlag,
+					ChildYesFlag,
+				},
+				Description: `
+    pchain child join --keyfile <file> | --usb [--privvalidator <file>] <chainId> <depositAmount>
+
+The consensus key is read from the priv_validator of the main chain in the datadir, the
+child chain is validated with the same key once launched. The sender is the account of
+the priv_validator, and the deposit (in wei) is locked until the child chain is launched
+or refunded.`,
+			},
+			{
+				Name:      "status",
+				Usage:     "Show the registration and the join progress of the child chain",
+				ArgsUsage: "<chainId>",
+				Action:    utils.MigrateFlags(childStatus),
+				Flags: []cli.Flag{
+					TxNodeFlag,
+				},
+			},
+		},
+	}
+)
+
+func childCreate(ctx *cli.Context) error {
+	if len(ctx.Args()) != 5 {
+		utils.Fatalf("The chainId, minValidators, minDepositAmount, startBlock and endBlock are required")
+	}
+	from, err := parseAddress(ctx.String(TxFromFlag.Name))
+	if err != nil {
+		utils.Fatalf("Invalid sender: %v", err)
+	}
+
+	chainId := ctx.Args()[0]
+	minValidators, err := strconv.ParseUint(ctx.Args()[1], 10, 16)
+	if err != nil {
+		utils.Fatalf("Invalid minValidators %q", ctx.Args()[1])
+	}
+	minDeposit := mustParseBig(ctx.Args()[2])
+	startBlock, endBlock := mustParseBig(ctx.Args()[3]), mustParseBig(ctx.Args()[4])
+
+	client := dialMainChain(ctx)
+	defer client.Close()
+
+	data, err := pchainclient.PackCreateChildChain(chainId, uint16(minValidators), minDeposit, startBlock, endBlock)
+	if err != nil {
+		utils.Fatalf("Failed to pack the built-in function: %v", err)
+	}
+
+	c, cancel := context.WithTimeout(context.Background(), txCallTimeout)
+	defer cancel()
+	tx, err := client.BuildBuiltinTx(c, from, nil, data)
+	if err != nil {
+		utils.Fatalf("Can not create the child chain: %v", err)
+	}
+	if err := checkChildTxCost(c, client, from, tx); err != nil {
+		utils.Fatalf("Can not create the child chain: %v", err)
+	}
+
+	fmt.Printf("Create child chain %s\n", chainId)
+	fmt.Printf("  Owner:              %x\n", from)
+	fmt.Printf("  Min validators:     %d\n", minValidators)
+	fmt.Printf("  Min deposit:        %s\n", formatPI(minDeposit))
+	fmt.Printf("  Start/end block:    %v - %v\n", startBlock, endBlock)
+	hash := signAndSendChildTx(ctx, client, from, tx)
+
+	fmt.Printf("Tx %s sent, the validators could join the child chain by:\n", hash.Hex())
+	fmt.Printf("    pchain child join --keyfile <file> %s <depositAmount>\n", chainId)
+	return nil
+}
+
+func childJoin(ctx *cli.Context) error {
+	if len(ctx.Args()) != 2 {
+		utils.Fatalf("The chainId and depositAmount are required")
+	}
+	chainId := ctx.Args()[0]
+	deposit := mustParseBig(ctx.Args()[1])
+
+	privVal := loadChildPrivValidator(ctx)
+	from := privVal.Address
+	if ctx.IsSet(TxFromFlag.Name) {
+		sender, err := parseAddress(ctx.String(TxFromFlag.Name))
+		if err != nil {
+			utils.Fatalf("Invalid sender: %v", err)
+		}
+		if sender != from {
+			utils.Fatalf("The sender must be the account of the priv_validator %x", from)
+		}
+	}
+
+	var pubkey crypto.BLSPubKey
+	if privVal.PubKey == nil || len(privVal.PubKey.Bytes()) != len(pubkey) {
+		utils.Fatalf("Invalid consensus public key in the priv_validator")
+	}
+	copy(pubkey[:], privVal.PubKey.Bytes())
+	if privVal.PrivKey == nil {
+		utils.Fatalf("No consensus private key in the priv_validator")
+	}
+	signature, err := pchainclient.SignBLSAddress(from, privVal.PrivKey.Bytes())
+	if err != nil {
+		utils.Fatalf("Failed to sign the address with the consensus key: %v", err)
+	}
+	if err := crypto.CheckConsensusPubKey(from, pubkey.Bytes(), signature); err != nil {
+		utils.Fatalf("Invalid priv_validator: %v", err)
+	}
+
+	client := dialMainChain(ctx)
+	defer client.Close()
+
+	c, cancel := context.WithTimeout(context.Background(), txCallTimeout)
+	defer cancel()
+	cci, err := pendingChildChain(c, client, chainId)
+	if err != nil {
+		utils.Fatalf("Can not join the child chain: %v", err)
+	}
+
+	data, err := pchainclient.PackJoinChildChain(pubkey, chainId, signature)
+	if err != nil {
+		utils.Fatalf("Failed to pack the built-in function: %v", err)
+	}
+	tx, err := client.BuildBuiltinTx(c, from, deposit, data)
+	if err != nil {
+		utils.Fatalf("Can not join the child chain: %v", err)
+	}
+	if err := checkChildTxCost(c, client, from, tx); err != nil {
+		utils.Fatalf("Can not join the child chain: %v", err)
+	}
+
+	fmt.Printf("Join child chain %s\n", chainId)
+	fmt.Printf("  Validator:          %x\n", from)
+	fmt.Printf("  Consensus pubkey:   %X\n", pubkey.Bytes())
+	fmt.Printf("  Deposit:            %s\n", formatPI(deposit))
+	fmt.Printf("  Joined validators:  %d (min %d) after the join\n", len(cci.JoinedValidators)+1, cci.MinValidators)
+	fmt.Printf("  Total deposit:      %s (min %s) after the join\n", formatPI(new(big.Int).Add(cci.TotalDeposit(), deposit)), formatPI(cci.MinDepositAmount))
+	hash := signAndSendChildTx(ctx, client, from, tx)
+
+	fmt.Printf("Tx %s sent, check the progress by:\n", hash.Hex())
+	fmt.Printf("    pchain child status %s\n", chainId)
+	return nil
+}
+
+func childStatus(ctx *cli.Context) error {
+	if len(ctx.Args()) != 1 {
+		utils.Fatalf("The chainId is required")
+	}
+	chainId := ctx.Args()[0]
+
+	client := dialMainChain(ctx)
+	defer client.Close()
+
+	c, cancel := context.WithTimeout(context.Background(), txCallTimeout)
+	defer cancel()
+	header, err := client.HeaderByNumber(c, nil)
+	if err != nil {
+		utils.Fatalf("Failed to get the current block: %v", err)
+	}
+	cci, pending, err := client.GetChildChain(c, chainId, header.Number)
+	if err != nil {
+		utils.Fatalf("Failed to get the child chain: %v", err)
+	}
+	if cci == nil {
+		utils.Fatalf("Child chain %s not found at block %v, it is not created or removed after the end block", chainId, header.Number)
+	}
+
+	fmt.Printf("Child chain %s at main chain block %v\n", chainId, header.Number)
+	fmt.Printf("  Status:             %s\n", childChainStatus(cci, pending, header.Number))
+	fmt.Printf("  Owner:              %x\n", cci.Owner)
+	fmt.Printf("  Start/end block:    %v - %v\n", cci.StartBlock, cci.EndBlock)
+	fmt.Printf("  Joined validators:  %d (min %d)\n", len(cci.JoinedValidators), cci.MinValidators)
+	fmt.Printf("  Total deposit:      %s (min %s)\n", formatPI(cci.TotalDeposit()), formatPI(cci.MinDepositAmount))
+	if !pending {
+		fmt.Printf("  Epoch:              %d\n", cci.EpochNumber)
+	}
+	for i, jv := range cci.JoinedValidators {
+		fmt.Printf("  [%d] %x  %s\n", i, jv.Address, formatPI(jv.DepositAmount))
+	}
+	return nil
+}
+
+// pendingChildChain returns the pending child chain to be joined. The join is checked by the validate callback
+// of the node when the tx is built, the child chain is only got for the join progress.
+func pendingChildChain(ctx context.Context, client *pchainclient.Client, chainId string) (*core.CoreChainInfo, error) {
+	header, err := client.HeaderByNumber(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	cci, pending, err := client.GetChildChain(ctx, chainId, header.Number)
+	if err != nil {
+		return nil, err
+	}
+	if cci == nil {
+		return nil, fmt.Errorf("child chain %s not found", chainId)
+	}
+	if !pending {
+		return nil, fmt.Errorf("child chain %s has already been launched", chainId)
+	}
+	if cci.EndBlock.Cmp(header.Number) <= 0 {
+		return nil, fmt.Errorf("end block %v has passed, the child chain will be removed", cci.EndBlock)
+	}
+	return cci, nil
+}
+
+// checkChildTxCost checks the sender could pay the value and the gas of the tx in the pending state
+func checkChildTxCost(ctx context.Context, client *pchainclient.Client, from common.Address, tx *types.Transaction) error {
+	balance, err := client.PendingBalanceAt(ctx, from)
+	if err != nil {
+		return err
+	}
+	if balance.Cmp(tx.Cost()) < 0 {
+		return fmt.Errorf("insufficient balance %s for the value and the gas %s", formatPI(balance), formatPI(tx.Cost()))
+	}
+	return nil
+}
+
+// childChainStatus describes the join progress of the pending child chain, see core.LaunchChildChains
+func childChainStatus(cci *core.CoreChainInfo, pending bool, number *big.Int) string {
+	if !pending {
+		return "launched"
+	}
+	ready := len(cci.JoinedValidators) >= int(cci.MinValidators) && cci.TotalDeposit().Cmp(cci.MinDepositAmount) >= 0
+	switch {
+	case !ready:
+		return "pending, waiting for the validators to join"
+	case number.Cmp(cci.StartBlock) < 0:
+		return fmt.Sprintf("pending, ready to launch at block %v", cci.StartBlock)
+	default:
+		return "pending, launching at the next block"
+	}
+}
+
+// signAndSendChildTx signs the tx of the sender offline after the confirmation and sends it, it returns the tx hash
+func signAndSendChildTx(ctx *cli.Context, client *pchainclient.Client, from common.Address, tx *types.Transaction) common.Hash {
+	if !ctx.Bool(ChildYesFlag.Name) {
+		ok, err := console.Stdin.PromptConfirm("Sign and send the tx?")
+		if err != nil {
+			utils.Fatalf("Failed to read the confirmation: %v", err)
+		}
+		if !ok {
+			utils.Fatalf("Aborted")
+		}
+	}
+
+	chainId := mainChainId(ctx)
+	var signed *types.Transaction
+	var err error
+	switch {
+	case ctx.IsSet(TxKeyFileFlag.Name):
+		signed, err = signTxWithKeyFile(ctx, tx, chainId)
+
+	case ctx.Bool(TxUSBFlag.Name):
+		signed, err = signTxWithUSB(ctx, tx, chainId)
+
+	default:
+		utils.Fatalf("Either --%s or --%s is required", TxKeyFileFlag.Name, TxUSBFlag.Name)
+	}
+	if err != nil {
+		utils.Fatalf("Failed to sign the tx: %v", err)
+	}
+	// the keyfile is not checked against the sender without --from
+	if sender, err := types.Sender(types.NewEIP155Signer(pchainclient.ChainIdBig(chainId)), signed); err != nil || sender != from {
+		utils.Fatalf("The tx is not signed by the sender %x", from)
+	}
+
+	c, cancel := context.WithTimeout(context.Background(), txCallTimeout)
+	defer cancel()
+	if err := client.SendTransaction(c, signed); err != nil {
+		utils.Fatalf("Failed to send the tx: %v", err)
+	}
+	return signed.Hash()
+}
+
+// loadChildPrivValidator loads the priv_validator given by --privvalidator, the one of the main chain by default.
+// The child chains are validated with the same key as the main chain.
+func loadChildPrivValidator(ctx *cli.Context) *tdmTypes.PrivValidator {
+	file := ctx.String(ChildPrivValidatorFlag.Name)
+	if file == "" {
+		file = chain.GetTendermintConfig(mainChainId(ctx), ctx).GetString("priv_validator_file")
+	}
+	if _, err := os.Stat(file); err != nil {
+		utils.Fatalf("Failed to read the priv_validator: %v", err)
+	}
+	return tdmTypes.LoadPrivValidator(file)
+}
+
+// mainChainId returns the main chain, the testnet with --testnet
+func mainChainId(ctx *cli.Context) string {
+	if ctx.GlobalBool(utils.TestnetFlag.Name) {
+		return chain.TestnetChain
+	}
+	return chain.MainChain
+}
+
+func dialMainChain(ctx *cli.Context) *pchainclient.Client {
+	client, err := pchainclient.Dial(ctx.String(TxNodeFlag.Name))
+	if err != nil {
+		utils.Fatalf("Failed to connect to the node: %v", err)
+	}
+	chainClient, err := client.ForChain(mainChainId(ctx))
+	if err != nil {
+		utils.Fatalf("Failed to connect to the main chain: %v", err)
+	}
+	return chainClient
+}
+
+// formatPI formats the amount in wei as PI
+func formatPI(wei *big.Int) string {
+	if wei == nil {
+		return "0 PI"
+	}
+	pi := new(big.Float).Quo(new(big.Float).SetInt(wei), big.NewFloat(params.PI))
+	return pi.Text('f', -1) + " PI"
+}
+
+func mustParseBig(s string) *big.Int {
+	b, err := parseBig(s)
+	if err != nil {
+		utils.Fatalf("Invalid number %q", s)
+	}
+	return b
+}
@@ -0,0 +1,169 @@
+package main
+
+import (
+	"github.com/ethereum/go-ethereum/cmd/geth"
+	"github.com/ethereum/go-ethereum/cmd/utils"
+	"github.com/ethereum/go-ethereum/console"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/pchain/chain"
+	"gopkg.in/urfave/cli.v1"
+	"net/url"
+	"path/filepath"
+	"strings"
+)
+
+var (
+	ConsoleChainFlag = cli.StringFlag{
+		Name:  "chain",
+		Usage: "Chain the console attaches to (default: the main chain)",
+	}
+
+	consoleFlags = []cli.Flag{utils.JSpathFlag, utils.ExecFlag, utils.PreloadJSFlag, ConsoleChainFlag}
+
+	consoleCommand = cli.Command{
+		Action:   utils.MigrateFlags(localConsole),
+		Name:     "console",
+		Usage:    "Start the node with an interactive JavaScript environment",
+		Flags:    consoleFlags,
+		Category: "CONSOLE COMMANDS",
+		Description: `
+
+The console is an interactive shell for the JavaScript runtime environment which
+exposes a node admin interface as well as the Ðapp JavaScript API. The node is
+started with the main chain and the child chains, the console attaches to the
+chain given by --chain. Switch to another chain run by the node with:
+
+    > pchain.use("child-1")`,
+	}
+
+	attachCommand = cli.Command{
+		Action:    utils.MigrateFlags(remoteConsole),
+		Name:      "attach",
+		Usage:     "Start an interactive JavaScript environment (connect to node)",
+		ArgsUsage: "[endpoint]",
+		Flags:     append(consoleFlags, utils.DataDirFlag),
+		Category:  "CONSOLE COMMANDS",
+		Description: `
+
+Open a console on a running node. The node serves each chain over HTTP at
+http://<host>:<port>/<chainId>, and over IPC at <datadir>/<chainId>/pchain.ipc.
+Without endpoint, the console attaches to the IPC of the chain in the datadir.
+
+    pchain attach [--chain <chainId>] [endpoint]
+
+The endpoint is switched to the chain given by --chain. Switch to another chain
+of the node in the console with:
+
+    > pchain.use("child-1")`,
+	}
+)
+
+// localConsole starts the node, attaching a JavaScript console to the chain in process
+func localConsole(ctx *cli.Context) error {
+	chainMgr, err := startPChain(ctx)
+	if err != nil {
+		return err
+	}
+	defer chainMgr.Stop()
+
+	chainId := consoleChainId(ctx)
+	client, err := chainMgr.AttachChain(chainId)
+	if err != nil {
+		utils.Fatalf("Failed to attach to chain %s: %v", chainId, err)
+	}
+	return runConsole(ctx, client, chainId, chainMgr.AttachChain)
+}
+
+// remoteConsole attaches a JavaScript console to the chain of a running node
+func remoteConsole(ctx *cli.Context) error {
+	chainId := consoleChainId(ctx)
+
+	endpoint := ctx.Args().First()
+	switch {
+	case endpoint == "":
+		endpoint = filepath.Join(utils.MakeDataDir(ctx), chainId, gethmain.DefaultNodeConfig().IPCPath)
+	case ctx.IsSet(ConsoleChainFlag.Name) || endpointChainId(endpoint) == "":
+		endpoint = chainEndpoint(endpoint, chainId)
+	default:
+		chainId = endpointChainId(endpoint)
+	}
+
+	client, err := rpc.Dial(endpoint)
+	if err != nil {
+		utils.Fatalf("Unable to attach to remote pchain: %v", err)
+	}
+	return runConsole(ctx, client, chainId, func(chainId string) (*rpc.Client, error) {
+		return rpc.Dial(chainEndpoint(endpoint, chainId))
+	})
+}
+
+// runConsole runs the console on the client of the chain, the dialer switches the console to another chain
+func runConsole(ctx *cli.Context, client *rpc.Client, chainId string, dialer func(chainId string) (*rpc.Client, error)) error {
+	config := console.Config{
+		DataDir: utils.MakeDataDir(ctx),
+		DocRoot: ctx.GlobalString(utils.JSpathFlag.Name),
+		Client:  client,
+		Preload: utils.MakeConsolePreloads(ctx),
+		ChainId: chainId,
+		Dialer:  dialer,
+	}
+
+	console, err := console.New(config)
+	if err != nil {
+		utils.Fatalf("Failed to start the JavaScript console: %v", err)
+	}
+	defer console.Stop(false)
+
+	// If only a short execution was requested, evaluate and return
+	if script := ctx.GlobalString(utils.ExecFlag.Name); script != "" {
+		console.Evaluate(script)
+		return nil
+	}
+	// Otherwise print the welcome screen and enter interactive mode
+	console.Welcome()
+	console.Interactive()
+
+	return nil
+}
+
+// consoleChainId returns the chain given by --chain, the main chain by default
+func consoleChainId(ctx *cli.Context) string {
+	if chainId := ctx.String(ConsoleChainFlag.Name); chainId != "" {
+		return chainId
+	}
+	if ctx.GlobalBool(utils.TestnetFlag.Name) {
+		return chain.TestnetChain
+	}
+	return chain.MainChain
+}
+
+// parseHTTPEndpoint parses the endpoint served by the rpc of the node, where the chains are distinguished
+// by the path. It returns false if the endpoint is not HTTP.
+func parseHTTPEndpoint(endpoint string) (*url.URL, bool) {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return nil, false
+	}
+	return u, u.Scheme == "http" || u.Scheme == "https"
+}
+
+// chainEndpoint returns the endpoint of the chain on the same node as the endpoint
+func chainEndpoint(endpoint, chainId string) string {
+	if u, ok := parseHTTPEndpoint(endpoint); ok {
+		u.Path = "/" + chainId
+		return u.String()
+	}
+	// The IPC endpoint is in the data directory of the chain
+	return filepath.Join(filepath.Dir(filepath.Dir(endpoint)), chainId, filepath.Base(endpoint))
+}
+
+// endpointChainId returns the chain of the endpoint, empty if the endpoint is not of a chain
+func endpointChainId(endpoint string) string {
+	if u, ok := parseHTTPEndpoint(endpoint); ok {
+		return strings.Trim(u.Path, "/")
+	}
+	if dir := filepath.Base(filepath.Dir(endpoint)); dir != "." && dir != string(filepath.Separator) {
+		return dir
+	}
+	return ""
+}
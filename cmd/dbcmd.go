@@ -0,0 +1,261 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/ethereum/go-ethereum/cmd/geth"
+	"github.com/ethereum/go-ethereum/cmd/utils"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/pchain/chain"
+	dbm "github.com/tendermint/go-db"
+	"gopkg.in/urfave/cli.v1"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+var (
+	DBChainFlag = cli.StringFlag{
+		Name:  "chain",
+		Usage: "Chain of the databases",
+		Value: clientIdentifier,
+	}
+
+	DBVerifyFlag = cli.BoolFlag{
+		Name:  "verify",
+		Usage: "Compare the rebuilt databases with the ones on disk instead of replacing them",
+	}
+
+	dbCommand = cli.Command{
+		Name:     "db",
+		Usage:    "Maintain the databases of a chain",
+		Category: "BLOCKCHAIN COMMANDS",
+		Description: `
+
+The epoch db, kept in the chain db, the chaininfo db and the tx3cache are only written as side effects
+of the inserted blocks, a node can't recover if they are lost or corrupted. The rebuild command replays the blocks of the chain
+to regenerate them, the node must be stopped.
+
+    pchain db rebuild [--chain <chainId>] [--verify]
+
+The epoch db of the chain is always rebuilt. The chaininfo db and the tx3cache are rebuilt with the
+main chain, the chaininfo db from the blocks before the child chain state fork, and the tx3s are read
+from the child chains run by the node. With --verify the rebuilt databases are compared with the ones
+on disk, and nothing is written.`,
+		Subcommands: []cli.Command{
+			{
+				Name:   "rebuild",
+				Usage:  "Rebuild the epoch, chaininfo and tx3cache databases from the blocks",
+				Action: utils.MigrateFlags(dbRebuild),
+				Flags: []cli.Flag{
+					utils.DataDirFlag,
+					utils.TX3CacheRetentionFlag,
+					DBChainFlag,
+					DBVerifyFlag,
+				},
+			},
+		},
+	}
+)
+
+// kvStore is a database rebuilt in memory, with the content of the one on disk
+type kvStore struct {
+	name    string
+	rebuilt map[string][]byte
+	disk    map[string][]byte
+	replace func(deleted []string, rebuilt map[string][]byte) error
+}
+
+func dbRebuild(ctx *cli.Context) error {
+	chainId := ctx.String(DBChainFlag.Name)
+	verify := ctx.Bool(DBVerifyFlag.Name)
+
+	chainDb, epochDB := openChainDBs(ctx, chainId)
+	defer chainDb.Close()
+	defer epochDB.Close()
+
+	head, err := chain.HeadNumber(chainDb)
+	if err != nil {
+		utils.Fatalf("Failed to read chain %s: %v", chainId, err)
+	}
+
+	var stores []*kvStore
+
+	// Epoch DB
+	newEpochDB := dbm.NewMemDB()
+	ep, err := chain.RebuildEpochs(chainDb, newEpochDB, genesisDoc(ctx, chainId), head)
+	if err != nil {
+		utils.Fatalf("Failed to rebuild the epoch db: %v", err)
+	}
+	fmt.Printf("Replayed %v blocks of chain %s, epoch %v\n", head, chainId, ep.Number)
+	stores = append(stores, dbmStore("epoch", newEpochDB, epochDB))
+
+	if chainId == chain.MainChain || chainId == chain.TestnetChain {
+		datadir := utils.MakeDataDir(ctx)
+
+		config, err := core.GetChainConfig(chainDb, core.GetCanonicalHash(chainDb, 0))
+		if err != nil {
+			utils.Fatalf("Failed to read the chain config: %v", err)
+		}
+
+		// Chain Info DB, written before the child chain state fork
+		chainInfoDB := dbm.NewDB("chaininfo", chain.GetTendermintConfig(chainId, ctx).GetString("db_backend"), datadir)
+		defer chainInfoDB.Close()
+
+		newChainInfoDB := dbm.NewMemDB()
+		if err := chain.RebuildChainInfo(chainDb, config, newChainInfoDB, head); err != nil {
+			utils.Fatalf("Failed to rebuild the chaininfo db: %v", err)
+		}
+		fmt.Printf("Rebuilt the chaininfo db of chain %s, hash %x\n", chainId, core.ChainInfoHash(newChainInfoDB))
+		stores = append(stores, dbmStore("chaininfo", newChainInfoDB, chainInfoDB))
+
+		// TX3 Cache, the tx3s are read from the child chains run by the node
+		var childChains core.ChainInfoStore = core.NewChainInfoDBStore(newChainInfoDB)
+		if config.IsChildChainState(new(big.Int).SetUint64(head)) {
+			headState, err := state.New(core.GetHeader(chainDb, core.GetCanonicalHash(chainDb, head), head).Root, state.NewDatabase(chainDb))
+			if err != nil {
+				utils.Fatalf("Failed to read the state of block %v: %v", head, err)
+			}
+			childChains = headState
+		}
+		childDbs := make(map[string]ethdb.Database)
+		for _, childId := range core.GetChildChainIds(childChains) {
+			dir := filepath.Join(datadir, childId, gethmain.ClientIdentifier, "chaindata")
+			if _, err := os.Stat(dir); err != nil {
+				fmt.Printf("Chain %s is not run by the node, its tx3s are not rebuilt\n", childId)
+				continue
+			}
+			childDb, err := ethdb.NewLDBDatabase(dir, 0, 0)
+			if err != nil {
+				utils.Fatalf("Could not open database of chain %s: %v", childId, err)
+			}
+			defer childDb.Close()
+			childDbs[childId] = childDb
+		}
+
+		tx3CacheDb, err := ethdb.NewLDBDatabase(filepath.Join(datadir, "tx3cache"), 0, 0)
+		if err != nil {
+			utils.Fatalf("Could not open database: %v", err)
+		}
+		defer tx3CacheDb.Close()
+
+		newTX3CacheDb, _ := ethdb.NewMemDatabase()
+		if err := chain.RebuildTX3Cache(chainDb, head, childDbs, newTX3CacheDb, ctx.Uint64(utils.TX3CacheRetentionFlag.Name)); err != nil {
+			utils.Fatalf("Failed to rebuild the tx3cache: %v", err)
+		}
+		stores = append(stores, ldbStore("tx3cache", newTX3CacheDb, tx3CacheDb))
+	}
+
+	diffs := 0
+	for _, s := range stores {
+		deleted, n := diffStore(s)
+		diffs += n
+
+		if !verify {
+			if err := s.replace(deleted, s.rebuilt); err != nil {
+				utils.Fatalf("Failed to write the %s db: %v", s.name, err)
+			}
+			fmt.Printf("Rebuilt the %s db, %v records\n", s.name, len(s.rebuilt))
+		}
+	}
+
+	if verify && diffs > 0 {
+		utils.Fatalf("%v records differ from the rebuilt databases", diffs)
+	}
+	return nil
+}
+
+// diffStore prints the records differ between the rebuilt db and the one on disk, it returns the keys only
+// found on disk and the number of the records differ
+func diffStore(s *kvStore) ([]string, int) {
+	var lines, deleted []string
+	for key, value := range s.rebuilt {
+		if old, ok := s.disk[key]; !ok {
+			lines = append(lines, fmt.Sprintf("  missing   %q", key))
+		} else if !bytes.Equal(old, value) {
+			lines = append(lines, fmt.Sprintf("  different %q", key))
+		}
+	}
+	for key := range s.disk {
+		if _, ok := s.rebuilt[key]; !ok {
+			lines = append(lines, fmt.Sprintf("  extra     %q", key))
+			deleted = append(deleted, key)
+		}
+	}
+
+	sort.Strings(lines)
+	fmt.Printf("%s db: %v records rebuilt, %v on disk, %v differ\n", s.name, len(s.rebuilt), len(s.disk), len(lines))
+	for _, line := range lines {
+		fmt.Println(line)
+	}
+	return deleted, len(lines)
+}
+
+// dbmStore makes the store of the tendermint db
+func dbmStore(name string, rebuilt, disk dbm.DB) *kvStore {
+	return &kvStore{
+		name:    name,
+		rebuilt: dbmRecords(rebuilt),
+		disk:    dbmRecords(disk),
+		replace: func(deleted []string, records map[string][]byte) error {
+			batch := disk.NewBatch()
+			for _, key := range deleted {
+				batch.Delete([]byte(key))
+			}
+			for key, value := range records {
+				batch.Set([]byte(key), value)
+			}
+			batch.Write()
+			return nil
+		},
+	}
+}
+
+func dbmRecords(db dbm.DB) map[string][]byte {
+	records := make(map[string][]byte)
+	it := db.Iterator()
+	for it.Next() {
+		records[string(it.Key())] = common.CopyBytes(it.Value())
+	}
+	return records
+}
+
+// ldbStore makes the store of the ethereum db
+func ldbStore(name string, rebuilt *ethdb.MemDatabase, disk *ethdb.LDBDatabase) *kvStore {
+	records := make(map[string][]byte)
+	for _, key := range rebuilt.Keys() {
+		records[string(key)], _ = rebuilt.Get(key)
+	}
+
+	diskRecords := make(map[string][]byte)
+	it := disk.NewIterator()
+	for it.Next() {
+		diskRecords[string(it.Key())] = common.CopyBytes(it.Value())
+	}
+	it.Release()
+
+	return &kvStore{
+		name:    name,
+		rebuilt: records,
+		disk:    diskRecords,
+		replace: func(deleted []string, records map[string][]byte) error {
+			// the deletes and the puts are written atomically by the batch
+			batch := disk.NewBatch()
+			for _, key := range deleted {
+				if err := batch.Delete([]byte(key)); err != nil {
+					return err
+				}
+			}
+			for key, value := range records {
+				if err := batch.Put([]byte(key), value); err != nil {
+					return err
+				}
+			}
+			return batch.Write()
+		},
+	}
+}
@@ -3,7 +3,6 @@ package main
 import (
 	"fmt"
 	"github.com/ethereum/go-ethereum/bridge"
-	"github.com/ethereum/go-ethereum/cmd/geth"
 	"github.com/ethereum/go-ethereum/cmd/utils"
 	"github.com/ethereum/go-ethereum/console"
 	"github.com/ethereum/go-ethereum/log"
@@ -69,12 +68,15 @@ func main() {
 		},
 
 		// See consolecmd.go:
-		//gethmain.ConsoleCommand,
-		gethmain.AttachCommand,
-		//gethmain.JavascriptCommand,
+		consoleCommand,
+		attachCommand,
 
 		//walletCommand,
 		accountCommand,
+		txCommand,
+		childCommand,
+		snapshotCommand,
+		dbCommand,
 	}
 	cliApp.HideVersion = true // we have a command to print the version
 
@@ -159,6 +161,7 @@ func newCliApp(version, usage string) *cli.App {
 		utils.TxPoolAccountQueueFlag,
 		utils.TxPoolGlobalQueueFlag,
 		utils.TxPoolLifetimeFlag,
+		utils.TX3CacheRetentionFlag,
 		//utils.FastSyncFlag,
 		//utils.LightModeFlag,
 		utils.SyncModeFlag,
@@ -216,6 +219,9 @@ func newCliApp(version, usage string) *cli.App {
 		*/
 		utils.IPCDisabledFlag,
 		utils.IPCPathFlag,
+		utils.JSpathFlag,
+		utils.ExecFlag,
+		utils.PreloadJSFlag,
 
 		utils.SolcPathFlag,
 		//utils.WhisperEnabledFlag,
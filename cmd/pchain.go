@@ -15,6 +15,21 @@ func pchainCmd(ctx *cli.Context) error {
 		return nil
 	}
 
+	chainMgr, err := startPChain(ctx)
+	if err != nil {
+		return err
+	}
+
+	chainMgr.WaitChainsStop()
+
+	chainMgr.Stop()
+
+	return nil
+}
+
+// startPChain starts the main chain and the child chains run by the node, with the rpc and the transfer manager
+func startPChain(ctx *cli.Context) (*chain.ChainManager, error) {
+
 	log.Info("Starting PChain...")
 	log.Info("PChain supports large scale block-chain applications with multi-chain")
 
@@ -30,7 +45,7 @@ func pchainCmd(ctx *cli.Context) error {
 	err := chainMgr.LoadMainChain(ctx)
 	if err != nil {
 		log.Errorf("Load Main Chain failed. %v", err)
-		return nil
+		return nil, err
 	}
 
 	//set the event.TypeMutex to cch
@@ -40,7 +55,7 @@ func pchainCmd(ctx *cli.Context) error {
 	err = chainMgr.StartP2PServer()
 	if err != nil {
 		log.Errorf("Start P2P Server failed. %v", err)
-		return err
+		return nil, err
 	}
 	consensus.NodeID = chainMgr.GetNodeID()[0:16]
 
@@ -51,27 +66,29 @@ func pchainCmd(ctx *cli.Context) error {
 	err = chainMgr.LoadChains(requestChildChain)
 	if err != nil {
 		log.Errorf("Load Child Chains failed. %v", err)
-		return err
+		return nil, err
 	}
 
 	// Start Child Chain
 	err = chainMgr.StartChains()
 	if err != nil {
 		log.Error("start chains failed")
-		return err
+		return nil, err
 	}
 
 	err = chainMgr.StartRPC()
 	if err != nil {
 		log.Error("start rpc failed")
-		return err
+		return nil, err
 	}
 
 	chainMgr.StartInspectEvent()
 
-	chainMgr.WaitChainsStop()
-
-	chainMgr.Stop()
+	err = chainMgr.StartTransferManager()
+	if err != nil {
+		log.Error("start transfer manager failed")
+		return nil, err
+	}
 
-	return nil
+	return chainMgr, nil
 }
@@ -0,0 +1,220 @@
+package main
+
+import (
+	"compress/gzip"
+	"fmt"
+	"github.com/ethereum/go-ethereum/cmd/geth"
+	"github.com/ethereum/go-ethereum/cmd/utils"
+	"github.com/ethereum/go-ethereum/consensus/tendermint/epoch"
+	tdmTypes "github.com/ethereum/go-ethereum/consensus/tendermint/types"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/eth"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/pchain/chain"
+	dbm "github.com/tendermint/go-db"
+	"gopkg.in/urfave/cli.v1"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+var (
+	SnapshotChainFlag = cli.StringFlag{
+		Name:  "chainId",
+		Usage: "Chain of the snapshot",
+		Value: clientIdentifier,
+	}
+
+	snapshotCommand = cli.Command{
+		Name:     "snapshot",
+		Usage:    "Export and import the state snapshots of a chain",
+		Category: "BLOCKCHAIN COMMANDS",
+		Description: `
+
+A snapshot is the state at the first block of an epoch, with the commit of the block and the
+epoch records. A new node imports the snapshot instead of replaying all the blocks, the snapshot
+is verified from the genesis validators of the chain. The node must be stopped.
+
+    pchain snapshot export [--chainId <chainId>] <epoch> <file>
+    pchain snapshot import [--chainId <chainId>] <file>
+
+A running node exports a snapshot or fetches one from its peers with admin.exportSnapshot(epoch, file)
+and admin.fetchSnapshot(epoch, file). The file is gzipped if the name ends with ".gz".`,
+		Subcommands: []cli.Command{
+			{
+				Name:      "export",
+				Usage:     "Export the snapshot at the first block of the epoch, 0 for the latest epoch",
+				ArgsUsage: "<epoch> <file>",
+				Action:    utils.MigrateFlags(snapshotExport),
+				Flags: []cli.Flag{
+					utils.DataDirFlag,
+					SnapshotChainFlag,
+				},
+			},
+			{
+				Name:      "import",
+				Usage:     "Restore the chain from the snapshot",
+				ArgsUsage: "<file>",
+				Action:    utils.MigrateFlags(snapshotImport),
+				Flags: []cli.Flag{
+					utils.DataDirFlag,
+					SnapshotChainFlag,
+				},
+			},
+		},
+	}
+)
+
+func snapshotExport(ctx *cli.Context) error {
+	if len(ctx.Args()) != 2 {
+		utils.Fatalf("This command requires two arguments: <epoch> <file>")
+	}
+	number, err := strconv.ParseUint(ctx.Args().Get(0), 10, 64)
+	if err != nil {
+		utils.Fatalf("Invalid epoch number: %v", err)
+	}
+
+	chainId := ctx.String(SnapshotChainFlag.Name)
+	chainDb, epochDB := openChainDBs(ctx, chainId)
+	defer chainDb.Close()
+	defer epochDB.Close()
+
+	if number == 0 {
+		latest, ok := epoch.LatestEpochNumber(epochDB)
+		if !ok {
+			utils.Fatalf("No epoch found for chain %s", chainId)
+		}
+		number = latest
+	}
+
+	var tx3s []*types.TX3ProofData
+	if tx3Db := openTX3CacheDB(ctx, chainId); tx3Db != nil {
+		defer tx3Db.Close()
+		tx3s = core.GetAllTX3ProofData(tx3Db)
+	}
+
+	s, err := eth.NewSnapshot(eth.NewDBSnapshotChain(chainDb), epochDB, number, tx3s)
+	if err != nil {
+		utils.Fatalf("Failed to make the snapshot: %v", err)
+	}
+
+	file := ctx.Args().Get(1)
+	out, err := os.OpenFile(file, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, os.ModePerm)
+	if err != nil {
+		utils.Fatalf("Failed to create the file: %v", err)
+	}
+	defer out.Close()
+
+	var writer io.Writer = out
+	if strings.HasSuffix(file, ".gz") {
+		writer = gzip.NewWriter(writer)
+		defer writer.(*gzip.Writer).Close()
+	}
+	if err := eth.ExportSnapshot(writer, s, state.NewDatabase(chainDb)); err != nil {
+		utils.Fatalf("Failed to export the snapshot: %v", err)
+	}
+	fmt.Printf("Exported the snapshot of epoch %v at block %v\n", number, s.Block.Number())
+	return nil
+}
+
+func snapshotImport(ctx *cli.Context) error {
+	if len(ctx.Args()) != 1 {
+		utils.Fatalf("This command requires an argument: <file>")
+	}
+
+	chainId := ctx.String(SnapshotChainFlag.Name)
+	trusted := genesisEpoch(ctx, chainId)
+
+	file := ctx.Args().First()
+	in, err := os.Open(file)
+	if err != nil {
+		utils.Fatalf("Failed to open the file: %v", err)
+	}
+	defer in.Close()
+
+	var reader io.Reader = in
+	if strings.HasSuffix(file, ".gz") {
+		if reader, err = gzip.NewReader(reader); err != nil {
+			utils.Fatalf("Failed to open the file: %v", err)
+		}
+	}
+
+	chainDb, epochDB := openChainDBs(ctx, chainId)
+	defer chainDb.Close()
+	defer epochDB.Close()
+
+	tx3Db := openTX3CacheDB(ctx, chainId)
+	if tx3Db != nil {
+		defer tx3Db.Close()
+	}
+
+	s, err := eth.ImportSnapshot(reader, chainDb, epochDB, tx3Db, trusted)
+	if err != nil {
+		utils.Fatalf("Failed to import the snapshot: %v", err)
+	}
+	fmt.Printf("Imported the snapshot at block %v, hash %x\n", s.Block.Number(), s.Block.Hash())
+	return nil
+}
+
+// openChainDBs opens the chain db and the epoch db kept in it
+func openChainDBs(ctx *cli.Context, chainId string) (ethdb.Database, dbm.DB) {
+	chainDb, err := ethdb.NewLDBDatabase(filepath.Join(utils.MakeDataDir(ctx), chainId, gethmain.ClientIdentifier, "chaindata"), 0, 0)
+	if err != nil {
+		utils.Fatalf("Could not open database: %v", err)
+	}
+
+	config := chain.GetTendermintConfig(chainId, ctx)
+	epochDB, err := epoch.OpenEpochDB(chainDb, config.GetString("db_backend"), config.GetString("db_dir"), nil)
+	if err != nil {
+		utils.Fatalf("Could not open the epoch db: %v", err)
+	}
+	return chainDb, epochDB
+}
+
+// openTX3CacheDB opens the tx3 cache of the main chain, it returns nil for the child chains
+func openTX3CacheDB(ctx *cli.Context, chainId string) ethdb.Database {
+	if chainId != chain.MainChain && chainId != chain.TestnetChain {
+		return nil
+	}
+	tx3Db, err := ethdb.NewLDBDatabase(filepath.Join(utils.MakeDataDir(ctx), "tx3cache"), 0, 0)
+	if err != nil {
+		utils.Fatalf("Could not open the tx3 cache: %v", err)
+	}
+	return tx3Db
+}
+
+// genesisEpoch returns the epoch in the tendermint genesis of the chain with its reward scheme, the validators
+// and the reward scheme of the snapshot are verified from it
+func genesisEpoch(ctx *cli.Context, chainId string) *epoch.Epoch {
+	genDoc := genesisDoc(ctx, chainId)
+	ep := epoch.MakeOneEpoch(nil, &genDoc.CurrentEpoch, nil)
+	ep.SetRewardScheme(epoch.MakeRewardScheme(nil, &genDoc.RewardScheme))
+	return ep
+}
+
+// genesisDoc returns the tendermint genesis of the chain
+func genesisDoc(ctx *cli.Context, chainId string) *tdmTypes.GenesisDoc {
+	var genDoc *tdmTypes.GenesisDoc
+	var err error
+
+	config := chain.GetTendermintConfig(chainId, ctx)
+	if jsonBlob, e := ioutil.ReadFile(config.GetString("genesis_file")); e == nil {
+		genDoc, err = tdmTypes.GenesisDocFromJSON(jsonBlob)
+	} else if chainId == params.MainnetChainConfig.PChainId {
+		genDoc, err = tdmTypes.GenesisDocFromJSON([]byte(tdmTypes.MainnetGenesisJSON))
+	} else if chainId == params.TestnetChainConfig.PChainId {
+		genDoc, err = tdmTypes.GenesisDocFromJSON([]byte(tdmTypes.TestnetGenesisJSON))
+	} else {
+		err = e
+	}
+	if err != nil {
+		utils.Fatalf("Failed to read the genesis of chain %s: %v", chainId, err)
+	}
+	return genDoc
+}
@@ -0,0 +1,529 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/accounts/keystore"
+	"github.com/ethereum/go-ethereum/accounts/usbwallet"
+	"github.com/ethereum/go-ethereum/cmd/utils"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/common/math"
+	"github.com/ethereum/go-ethereum/console"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/node"
+	"github.com/ethereum/go-ethereum/rlp"
+	pabi "github.com/pchain/abi"
+	"github.com/pchain/pchainclient"
+	"github.com/tendermint/go-crypto"
+	"gopkg.in/urfave/cli.v1"
+	"io/ioutil"
+	"math/big"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	TxNodeFlag = cli.StringFlag{
+		Name:  "node",
+		Usage: "RPC endpoint of the node to fetch the nonce and gas, and to send the tx",
+		Value: fmt.Sprintf("http://localhost:%d", node.DefaultHTTPPort),
+	}
+	TxChainFlag = cli.StringFlag{
+		Name:  "chainId",
+		Usage: "Chain which the tx is sent to",
+		Value: clientIdentifier,
+	}
+	TxFromFlag = cli.StringFlag{
+		Name:  "from",
+		Usage: "Sender of the tx",
+	}
+	TxValueFlag = cli.StringFlag{
+		Name:  "value",
+		Usage: "Amount (in wei) sent with the tx",
+	}
+	TxKeyFileFlag = cli.StringFlag{
+		Name:  "keyfile",
+		Usage: "Keystore file of the sender to sign the tx",
+	}
+	TxUSBFlag = cli.BoolFlag{
+		Name:  "usb",
+		Usage: "Sign the tx with the USB hardware wallet (Ledger or Trezor)",
+	}
+	TxHDPathFlag = cli.StringFlag{
+		Name:  "hdpath",
+		Usage: "Derivation path of the sender in the USB hardware wallet",
+		Value: accounts.DefaultBaseDerivationPath.String(),
+	}
+
+	txCallTimeout = 30 * time.Second
+
+	txCommand = cli.Command{
+		Name:     "tx",
+		Usage:    "Build, sign offline and send the PChain built-in function txs",
+		Category: "ACCOUNT COMMANDS",
+		Description: `
+
+Build the tx calling the built-in function with the nonce and gas fetched from a node,
+sign it offline with a keystore file or USB hardware wallet, then send the signed tx
+through eth_sendRawTransaction. The sender's account never needs to be unlocked on the node.
+
+    pchain tx build --from <address> [--value <wei>] <function> [arguments...]
+    pchain tx sign --keyfile <file> | --usb --from <address> <unsigned tx>
+    pchain tx send <signed tx>`,
+		Subcommands: []cli.Command{
+			{
+				Name:      "build",
+				Usage:     "Build the unsigned tx of the built-in function",
+				ArgsUsage: "<function> [arguments...]",
+				Action:    utils.MigrateFlags(txBuild),
+				Flags: []cli.Flag{
+					TxNodeFlag,
+					TxChainFlag,
+					TxFromFlag,
+					TxValueFlag,
+				},
+				Description: `
+    pchain tx build --from <address> [--value <wei>] <function> [arguments...]
+
+Prints the rlp encoded unsigned tx in hex. The functions and their arguments are:
+
+    CreateChildChain <chainId> <minValidators> <minDepositAmount> <startBlock> <endBlock>
+    JoinChildChain <consensus pubkey> <chainId> <signature>   (--value is the deposit)
+    DepositInMainChain <chainId>                               (--value is the amount)
+    DepositInChildChain <tx hash>
+    WithdrawFromChildChain                                     (--value is the amount)
+    WithdrawFromMainChain <chainId> <amount> <tx hash>
+    VoteNextEpoch <vote hash>
+    RevealVote <consensus pubkey> <amount> <salt> <signature>
+    Delegate <candidate>                                       (--value is the amount)
+    CancelDelegate <candidate> <amount>
+    Candidate <commission>                                     (--value is the security deposit)
+    CancelCandidate
+    SubmitProposal <param> <value>                             (--value is the deposit)
+    VoteProposal <proposal id> <true|false>
+    DepositProposal <proposal id>                              (--value is the deposit)`,
+			},
+			{
+				Name:      "sign",
+				Usage:     "Sign the unsigned tx offline",
+				ArgsUsage: "<unsigned tx>",
+				Action:    utils.MigrateFlags(txSign),
+				Flags: []cli.Flag{
+					TxChainFlag,
+					TxFromFlag,
+					TxKeyFileFlag,
+					TxUSBFlag,
+					TxHDPathFlag,
+					utils.PasswordFileFlag,
+				},
+				Description: `
+    pchain tx sign --keyfile <file> <unsigned tx>
+    pchain tx sign --usb --from <address> [--hdpath <path>] <unsigned tx>
+
+Prints the rlp encoded signed tx in hex. No node is needed.`,
+			},
+			{
+				Name:      "send",
+				Usage:     "Send the signed tx through eth_sendRawTransaction",
+				ArgsUsage: "<signed tx>",
+				Action:    utils.MigrateFlags(txSend),
+				Flags: []cli.Flag{
+					TxNodeFlag,
+					TxChainFlag,
+				},
+				Description: `
+    pchain tx send <signed tx>
+
+Prints the hash of the tx.`,
+			},
+		},
+	}
+)
+
+func txBuild(ctx *cli.Context) error {
+	if len(ctx.Args()) == 0 {
+		return errors.New("the built-in function is required")
+	}
+	from, err := parseAddress(ctx.String(TxFromFlag.Name))
+	if err != nil {
+		return fmt.Errorf("invalid sender: %v", err)
+	}
+	chainId := ctx.String(TxChainFlag.Name)
+
+	value := new(big.Int)
+	if ctx.IsSet(TxValueFlag.Name) {
+		if value, err = parseBig(ctx.String(TxValueFlag.Name)); err != nil {
+			return fmt.Errorf("invalid value: %v", err)
+		}
+	}
+
+	data, err := packBuiltinCall(chainId, ctx.Args()[0], ctx.Args()[1:])
+	if err != nil {
+		return fmt.Errorf("failed to pack the built-in function: %v", err)
+	}
+
+	client, err := dialChain(ctx)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	c, cancel := context.WithTimeout(context.Background(), txCallTimeout)
+	defer cancel()
+	tx, err := client.BuildBuiltinTx(c, from, value, data)
+	if err != nil {
+		return fmt.Errorf("failed to build the tx: %v", err)
+	}
+
+	bs, err := rlp.EncodeToBytes(tx)
+	if err != nil {
+		return fmt.Errorf("failed to encode the tx: %v", err)
+	}
+	fmt.Println(hexutil.Encode(bs))
+	return nil
+}
+
+func txSign(ctx *cli.Context) error {
+	tx, err := decodeTxArg(ctx)
+	if err != nil {
+		return err
+	}
+	chainId := ctx.String(TxChainFlag.Name)
+
+	var signed *types.Transaction
+	switch {
+	case ctx.IsSet(TxKeyFileFlag.Name):
+		signed, err = signTxWithKeyFile(ctx, tx, chainId)
+
+	case ctx.Bool(TxUSBFlag.Name):
+		signed, err = signTxWithUSB(ctx, tx, chainId)
+
+	default:
+		return fmt.Errorf("either --%s or --%s is required", TxKeyFileFlag.Name, TxUSBFlag.Name)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to sign the tx: %v", err)
+	}
+
+	bs, err := rlp.EncodeToBytes(signed)
+	if err != nil {
+		return fmt.Errorf("failed to encode the tx: %v", err)
+	}
+	fmt.Println(hexutil.Encode(bs))
+	return nil
+}
+
+func txSend(ctx *cli.Context) error {
+	tx, err := decodeTxArg(ctx)
+	if err != nil {
+		return err
+	}
+
+	client, err := dialChain(ctx)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	c, cancel := context.WithTimeout(context.Background(), txCallTimeout)
+	defer cancel()
+	if err := client.SendTransaction(c, tx); err != nil {
+		return fmt.Errorf("failed to send the tx: %v", err)
+	}
+	fmt.Println(tx.Hash().Hex())
+	return nil
+}
+
+// signTxWithKeyFile signs the tx with the key decrypted from the keystore file
+func signTxWithKeyFile(ctx *cli.Context, tx *types.Transaction, chainId string) (*types.Transaction, error) {
+	keyJson, err := ioutil.ReadFile(ctx.String(TxKeyFileFlag.Name))
+	if err != nil {
+		return nil, err
+	}
+	password := getPassPhrase("", false, 0, utils.MakePasswordList(ctx))
+	key, err := keystore.DecryptKey(keyJson, password)
+	if err != nil {
+		return nil, err
+	}
+	if ctx.IsSet(TxFromFlag.Name) {
+		from, err := parseAddress(ctx.String(TxFromFlag.Name))
+		if err != nil {
+			return nil, fmt.Errorf("invalid sender: %v", err)
+		}
+		if key.Address != from {
+			return nil, fmt.Errorf("the keyfile is for %x, not the sender", key.Address)
+		}
+	}
+	return pchainclient.SignTx(tx, chainId, key.PrivateKey)
+}
+
+// signTxWithUSB signs the tx with the account of the derivation path in the first USB wallet which has it
+func signTxWithUSB(ctx *cli.Context, tx *types.Transaction, chainId string) (*types.Transaction, error) {
+	from, err := parseAddress(ctx.String(TxFromFlag.Name))
+	if err != nil {
+		return nil, fmt.Errorf("--%s is required to find the account in the USB wallet: %v", TxFromFlag.Name, err)
+	}
+	path, err := accounts.ParseDerivationPath(ctx.String(TxHDPathFlag.Name))
+	if err != nil {
+		return nil, err
+	}
+
+	var wallets []accounts.Wallet
+	if hub, err := usbwallet.NewLedgerHub(); err == nil {
+		wallets = append(wallets, hub.Wallets()...)
+	}
+	if hub, err := usbwallet.NewTrezorHub(); err == nil {
+		wallets = append(wallets, hub.Wallets()...)
+	}
+
+	for _, wallet := range wallets {
+		err := wallet.Open("")
+		if err == usbwallet.ErrTrezorPINNeeded {
+			pin, perr := console.Stdin.PromptPassword("Trezor PIN: ")
+			if perr != nil {
+				return nil, perr
+			}
+			err = wallet.Open(pin)
+		}
+		if err != nil {
+			continue
+		}
+		account, err := wallet.Derive(path, false)
+		if err != nil || account.Address != from {
+			wallet.Close()
+			continue
+		}
+
+		fmt.Println("Please confirm the tx on the USB wallet")
+		signed, err := wallet.SignTx(account, tx, pchainclient.ChainIdBig(chainId))
+		wallet.Close()
+		return signed, err
+	}
+	return nil, fmt.Errorf("account %x of path %s not found in the USB wallets", from, path)
+}
+
+func dialChain(ctx *cli.Context) (*pchainclient.Client, error) {
+	client, err := pchainclient.Dial(ctx.String(TxNodeFlag.Name))
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to the node: %v", err)
+	}
+	chainClient, err := client.ForChain(ctx.String(TxChainFlag.Name))
+	if err != nil {
+		client.Close()
+		return nil, fmt.Errorf("failed to connect to the chain: %v", err)
+	}
+	return chainClient, nil
+}
+
+// decodeTxArg decodes the rlp encoded tx of the only argument
+func decodeTxArg(ctx *cli.Context) (*types.Transaction, error) {
+	if len(ctx.Args()) != 1 {
+		return nil, errors.New("the rlp encoded tx is required")
+	}
+	bs, err := hexutil.Decode(strings.TrimSpace(ctx.Args()[0]))
+	if err != nil {
+		return nil, fmt.Errorf("invalid tx: %v", err)
+	}
+	tx := new(types.Transaction)
+	if err := rlp.DecodeBytes(bs, tx); err != nil {
+		return nil, fmt.Errorf("invalid tx: %v", err)
+	}
+	return tx, nil
+}
+
+// packBuiltinCall packs the built-in function call from the command line arguments
+func packBuiltinCall(chainId string, name string, args []string) ([]byte, error) {
+	// number of the arguments of the functions
+	argc := map[pabi.FunctionType]int{
+		pabi.CreateChildChain:       5,
+		pabi.JoinChildChain:         3,
+		pabi.DepositInMainChain:     1,
+		pabi.DepositInChildChain:    1,
+		pabi.WithdrawFromChildChain: 0,
+		pabi.WithdrawFromMainChain:  3,
+		pabi.VoteNextEpoch:          1,
+		pabi.RevealVote:             4,
+		pabi.Delegate:               1,
+		pabi.CancelDelegate:         2,
+		pabi.Candidate:              1,
+		pabi.CancelCandidate:        0,
+		pabi.SubmitProposal:         2,
+		pabi.VoteProposal:           2,
+		pabi.DepositProposal:        1,
+	}
+	function := pabi.Unknown
+	for f := range argc {
+		if strings.EqualFold(f.String(), name) {
+			function = f
+			break
+		}
+	}
+	n, ok := argc[function]
+	if !ok {
+		return nil, fmt.Errorf("unsupported built-in function %q", name)
+	}
+	if len(args) != n {
+		return nil, fmt.Errorf("%v needs %d argument(s), got %d", function, n, len(args))
+	}
+
+	switch function {
+	case pabi.CreateChildChain:
+		minValidators, err := strconv.ParseUint(args[1], 10, 16)
+		if err != nil {
+			return nil, err
+		}
+		minDeposit, err := parseBig(args[2])
+		if err != nil {
+			return nil, err
+		}
+		startBlock, err := parseBig(args[3])
+		if err != nil {
+			return nil, err
+		}
+		endBlock, err := parseBig(args[4])
+		if err != nil {
+			return nil, err
+		}
+		return pchainclient.PackCreateChildChain(args[0], uint16(minValidators), minDeposit, startBlock, endBlock)
+	case pabi.JoinChildChain:
+		pubkey, err := parseBLSPubKey(args[0])
+		if err != nil {
+			return nil, err
+		}
+		signature, err := hexutil.Decode(args[2])
+		if err != nil {
+			return nil, err
+		}
+		return pchainclient.PackJoinChildChain(pubkey, args[1], signature)
+	case pabi.DepositInMainChain:
+		return pchainclient.PackDepositInMainChain(args[0])
+	case pabi.DepositInChildChain:
+		txHash, err := parseHash(args[0])
+		if err != nil {
+			return nil, err
+		}
+		return pchainclient.PackDepositInChildChain(chainId, txHash)
+	case pabi.WithdrawFromChildChain:
+		return pchainclient.PackWithdrawFromChildChain(chainId)
+	case pabi.WithdrawFromMainChain:
+		amount, err := parseBig(args[1])
+		if err != nil {
+			return nil, err
+		}
+		txHash, err := parseHash(args[2])
+		if err != nil {
+			return nil, err
+		}
+		return pchainclient.PackWithdrawFromMainChain(args[0], amount, txHash)
+	case pabi.VoteNextEpoch:
+		voteHash, err := parseHash(args[0])
+		if err != nil {
+			return nil, err
+		}
+		return pchainclient.PackVoteNextEpoch(voteHash)
+	case pabi.RevealVote:
+		pubkey, err := parseBLSPubKey(args[0])
+		if err != nil {
+			return nil, err
+		}
+		amount, err := parseBig(args[1])
+		if err != nil {
+			return nil, err
+		}
+		signature, err := hexutil.Decode(args[3])
+		if err != nil {
+			return nil, err
+		}
+		return pchainclient.PackRevealVote(pubkey, amount, args[2], signature)
+	case pabi.Delegate:
+		candidate, err := parseAddress(args[0])
+		if err != nil {
+			return nil, err
+		}
+		return pchainclient.PackDelegate(candidate)
+	case pabi.CancelDelegate:
+		candidate, err := parseAddress(args[0])
+		if err != nil {
+			return nil, err
+		}
+		amount, err := parseBig(args[1])
+		if err != nil {
+			return nil, err
+		}
+		return pchainclient.PackCancelDelegate(candidate, amount)
+	case pabi.Candidate:
+		commission, err := strconv.ParseUint(args[0], 10, 8)
+		if err != nil {
+			return nil, err
+		}
+		return pchainclient.PackApplyCandidate(uint8(commission))
+	case pabi.SubmitProposal:
+		value, err := parseBig(args[1])
+		if err != nil {
+			return nil, err
+		}
+		return pchainclient.PackSubmitProposal(args[0], value)
+	case pabi.VoteProposal:
+		id, err := parseHash(args[0])
+		if err != nil {
+			return nil, err
+		}
+		approve, err := strconv.ParseBool(args[1])
+		if err != nil {
+			return nil, err
+		}
+		return pchainclient.PackVoteProposal(id, approve)
+	case pabi.DepositProposal:
+		id, err := parseHash(args[0])
+		if err != nil {
+			return nil, err
+		}
+		return pchainclient.PackDepositProposal(id)
+	default:
+		return pchainclient.PackCancelCandidate()
+	}
+}
+
+func parseBLSPubKey(s string) (crypto.BLSPubKey, error) {
+	var pubkey crypto.BLSPubKey
+	bs, err := hexutil.Decode(s)
+	if err != nil {
+		return pubkey, err
+	}
+	if len(bs) != len(pubkey) {
+		return pubkey, errors.New("invalid consensus public key")
+	}
+	copy(pubkey[:], bs)
+	return pubkey, nil
+}
+
+// parseAddress parses the hex address, which must be 20 bytes
+func parseAddress(s string) (common.Address, error) {
+	if !common.IsHexAddress(s) {
+		return common.Address{}, fmt.Errorf("invalid address %q", s)
+	}
+	return common.HexToAddress(s), nil
+}
+
+// parseHash parses the 0x prefixed hex hash, which must be 32 bytes
+func parseHash(s string) (common.Hash, error) {
+	var hash common.Hash
+	bs, err := hexutil.Decode(s)
+	if err != nil || len(bs) != len(hash) {
+		return hash, fmt.Errorf("invalid hash %q", s)
+	}
+	return common.BytesToHash(bs), nil
+}
+
+// parseBig parses the decimal or 0x prefixed hex number
+func parseBig(s string) (*big.Int, error) {
+	b, ok := math.ParseBig256(s)
+	if !ok {
+		return nil, fmt.Errorf("invalid number %q", s)
+	}
+	return b, nil
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"bytes"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/pchain/pchainclient"
+)
+
+// Tests the built-in function calls are packed from the arguments the same as the client packs them.
+func TestPackBuiltinCall(t *testing.T) {
+	candidate := common.HexToAddress("0x1000000000000000000000000000000000000001")
+	id := common.HexToHash("0x01")
+
+	data, err := packBuiltinCall("pchain", "delegate", []string{candidate.Hex()})
+	if err != nil {
+		t.Fatalf("failed to pack Delegate: %v", err)
+	}
+	if want, _ := pchainclient.PackDelegate(candidate); !bytes.Equal(data, want) {
+		t.Errorf("Delegate mismatch: have %x, want %x", data, want)
+	}
+
+	data, err = packBuiltinCall("pchain", "CancelDelegate", []string{candidate.Hex(), "0x10"})
+	if err != nil {
+		t.Fatalf("failed to pack CancelDelegate: %v", err)
+	}
+	if want, _ := pchainclient.PackCancelDelegate(candidate, big.NewInt(16)); !bytes.Equal(data, want) {
+		t.Errorf("CancelDelegate mismatch: have %x, want %x", data, want)
+	}
+
+	data, err = packBuiltinCall("pchain", "VoteProposal", []string{id.Hex(), "true"})
+	if err != nil {
+		t.Fatalf("failed to pack VoteProposal: %v", err)
+	}
+	if want, _ := pchainclient.PackVoteProposal(id, true); !bytes.Equal(data, want) {
+		t.Errorf("VoteProposal mismatch: have %x, want %x", data, want)
+	}
+}
+
+// Tests the malformed hashes, addresses and numbers are rejected instead of being packed as zero or truncated.
+func TestPackBuiltinCallInvalid(t *testing.T) {
+	hash := common.HexToHash("0x01").Hex()
+	tests := []struct {
+		name string
+		args []string
+	}{
+		{"Delegate", []string{"0x1234"}},
+		{"Delegate", []string{"candidate"}},
+		{"CancelDelegate", []string{"0x10000000000000000000000000000000000000zz", "1"}},
+		{"CancelDelegate", []string{"0x1000000000000000000000000000000000000001", "one"}},
+		{"DepositInChildChain", []string{"0x01"}},
+		{"DepositInChildChain", []string{hash[2:]}},
+		{"WithdrawFromMainChain", []string{"child_0", "1", hash + "00"}},
+		{"WithdrawFromMainChain", []string{"child_0", "-1x", hash}},
+		{"VoteNextEpoch", []string{"vote"}},
+		{"VoteProposal", []string{"0x02", "true"}},
+		{"VoteProposal", []string{hash, "yes"}},
+		{"DepositProposal", []string{""}},
+		{"SubmitProposal", []string{"MinSignedPercent", "ten"}},
+		{"CreateChildChain", []string{"child_0", "1", "100", "10", "end"}},
+		{"RevealVote", []string{"0x01", "1", "salt", "0x01"}},
+		{"Delegate", nil},
+		{"Unknown", nil},
+	}
+	for i, tt := range tests {
+		if _, err := packBuiltinCall("pchain", tt.name, tt.args); err == nil {
+			t.Errorf("test %d: %s %v packed, want error", i, tt.name, tt.args)
+		}
+	}
+}
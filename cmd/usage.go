@@ -79,6 +79,7 @@ var AppHelpFlagGroups = []flagGroup{
 			utils.GCModeFlag,
 			utils.EthStatsURLFlag,
 			utils.IdentityFlag,
+			utils.TX3CacheRetentionFlag,
 			//utils.LightServFlag,
 			//utils.LightPeersFlag,
 			//utils.LightKDFFlag,
@@ -164,9 +165,9 @@ var AppHelpFlagGroups = []flagGroup{
 			utils.IPCPathFlag,
 			utils.RPCCORSDomainFlag,
 			//utils.RPCVirtualHostsFlag,
-			//utils.JSpathFlag,
-			//utils.ExecFlag,
-			//utils.PreloadJSFlag,
+			utils.JSpathFlag,
+			utils.ExecFlag,
+			utils.PreloadJSFlag,
 		},
 	},
 	{
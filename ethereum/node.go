@@ -50,22 +50,20 @@ func MakeSystemNode(chainId, version string, ctx *cli.Context, cch core.CrossCha
 
 // registerEthService adds an Ethereum client to the stack.
 func registerEthService(stack *node.Node, cfg *eth.Config, cliCtx *cli.Context, cch core.CrossChainHelper, mining bool) {
-	var err error
+	// The light sync mode of the tendermint chains is run by eth too, the node only verifies the epochs and
+	// serves the verified state, so it never mines
 	if cfg.SyncMode == downloader.LightSync {
-		err = stack.Register(func(ctx *node.ServiceContext) (node.Service, error) {
-			return les.New(ctx, cfg, nil, stack.GetLogger())
-		})
-	} else {
-		err = stack.Register(func(ctx *node.ServiceContext) (node.Service, error) {
-			//return NewBackend(ctx, cfg, cliCtx, pNode, cch)
-			fullNode, err := eth.New(ctx, cfg, cliCtx, cch, stack.GetLogger(), cliCtx.GlobalBool(utils.TestnetFlag.Name), mining)
-			if fullNode != nil && cfg.LightServ > 0 {
-				ls, _ := les.NewLesServer(fullNode, cfg)
-				fullNode.AddLesServer(ls)
-			}
-			return fullNode, err
-		})
+		mining = false
 	}
+	err := stack.Register(func(ctx *node.ServiceContext) (node.Service, error) {
+		//return NewBackend(ctx, cfg, cliCtx, pNode, cch)
+		fullNode, err := eth.New(ctx, cfg, cliCtx, cch, stack.GetLogger(), cliCtx.GlobalBool(utils.TestnetFlag.Name), mining)
+		if fullNode != nil && cfg.LightServ > 0 {
+			ls, _ := les.NewLesServer(fullNode, cfg)
+			fullNode.AddLesServer(ls)
+		}
+		return fullNode, err
+	})
 	if err != nil {
 		utils.Fatalf("Failed to register the Ethereum service: %v", err)
 	}
@@ -0,0 +1,157 @@
+package pchainclient
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"errors"
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	ethcrypto "github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
+	pabi "github.com/pchain/abi"
+	"github.com/tendermint/go-crypto"
+	"math/big"
+)
+
+// The Pack functions return the input data of the built-in function calls, the data is sent
+// to pabi.ChainContractMagicAddr by a tx built with NewBuiltinTx, which could be signed offline.
+
+func PackCreateChildChain(chainId string, minValidators uint16, minDepositAmount, startBlock, endBlock *big.Int) ([]byte, error) {
+	return pabi.ChainABI.Pack(pabi.CreateChildChain.String(), chainId, minValidators, minDepositAmount, startBlock, endBlock)
+}
+
+func PackJoinChildChain(pubkey crypto.BLSPubKey, chainId string, signature []byte) ([]byte, error) {
+	return pabi.ChainABI.Pack(pabi.JoinChildChain.String(), pubkey.Bytes(), chainId, signature)
+}
+
+func PackDepositInMainChain(chainId string) ([]byte, error) {
+	return pabi.ChainABI.Pack(pabi.DepositInMainChain.String(), chainId)
+}
+
+func PackDepositInChildChain(chainId string, txHash common.Hash) ([]byte, error) {
+	return pabi.ChainABI.Pack(pabi.DepositInChildChain.String(), chainId, txHash)
+}
+
+func PackWithdrawFromChildChain(chainId string) ([]byte, error) {
+	return pabi.ChainABI.Pack(pabi.WithdrawFromChildChain.String(), chainId)
+}
+
+func PackWithdrawFromMainChain(chainId string, amount *big.Int, txHash common.Hash) ([]byte, error) {
+	return pabi.ChainABI.Pack(pabi.WithdrawFromMainChain.String(), chainId, amount, txHash)
+}
+
+func PackVoteNextEpoch(voteHash common.Hash) ([]byte, error) {
+	return pabi.ChainABI.Pack(pabi.VoteNextEpoch.String(), voteHash)
+}
+
+func PackRevealVote(pubkey crypto.BLSPubKey, amount *big.Int, salt string, signature []byte) ([]byte, error) {
+	return pabi.ChainABI.Pack(pabi.RevealVote.String(), pubkey.Bytes(), amount, salt, signature)
+}
+
+func PackDelegate(candidate common.Address) ([]byte, error) {
+	return pabi.ChainABI.Pack(pabi.Delegate.String(), candidate)
+}
+
+func PackCancelDelegate(candidate common.Address, amount *big.Int) ([]byte, error) {
+	return pabi.ChainABI.Pack(pabi.CancelDelegate.String(), candidate, amount)
+}
+
+func PackApplyCandidate(commission uint8) ([]byte, error) {
+	return pabi.ChainABI.Pack(pabi.Candidate.String(), commission)
+}
+
+func PackCancelCandidate() ([]byte, error) {
+	return pabi.ChainABI.Pack(pabi.CancelCandidate.String())
+}
+
+func PackSubmitProposal(param string, value *big.Int) ([]byte, error) {
+	return pabi.ChainABI.Pack(pabi.SubmitProposal.String(), param, value)
+}
+
+func PackVoteProposal(id common.Hash, approve bool) ([]byte, error) {
+	return pabi.ChainABI.Pack(pabi.VoteProposal.String(), id, approve)
+}
+
+func PackDepositProposal(id common.Hash) ([]byte, error) {
+	return pabi.ChainABI.Pack(pabi.DepositProposal.String(), id)
+}
+
+// VoteHash returns the hash to be sent by VoteNextEpoch, the same arguments are revealed by RevealVote later
+func VoteHash(from common.Address, pubkey crypto.BLSPubKey, amount *big.Int, salt string) common.Hash {
+	return ethcrypto.Keccak256Hash(from.Bytes(), pubkey.Bytes(), amount.Bytes(), []byte(salt))
+}
+
+// SignBLSAddress signs the address with the consensus private key, the signature proves the
+// ownership of the consensus key in JoinChildChain and RevealVote
+func SignBLSAddress(from common.Address, consensusPrivateKey []byte) ([]byte, error) {
+	if len(consensusPrivateKey) != 32 {
+		return nil, errors.New("invalid consensus private key")
+	}
+
+	var blsPriv crypto.BLSPrivKey
+	copy(blsPriv[:], consensusPrivateKey)
+	return blsPriv.Sign(from.Bytes()).Bytes(), nil
+}
+
+// NewBuiltinTx returns the unsigned tx which calls the built-in function with the data
+func NewBuiltinTx(nonce uint64, value *big.Int, gasLimit uint64, gasPrice *big.Int, data []byte) *types.Transaction {
+	if value == nil {
+		value = new(big.Int)
+	}
+	return types.NewTransaction(nonce, pabi.ChainContractMagicAddr, value, gasLimit, gasPrice, data)
+}
+
+// ChainIdBig returns the chain id used by the replay protection of the chain
+func ChainIdBig(chainId string) *big.Int {
+	switch chainId {
+	case params.MainnetChainConfig.PChainId:
+		return params.MainnetChainConfig.ChainId
+	case params.TestnetChainConfig.PChainId:
+		return params.TestnetChainConfig.ChainId
+	default:
+		return params.NewChildChainConfig(chainId).ChainId
+	}
+}
+
+// SignTx signs the tx for the chain with the private key
+func SignTx(tx *types.Transaction, chainId string, prv *ecdsa.PrivateKey) (*types.Transaction, error) {
+	return types.SignTx(tx, types.NewEIP155Signer(ChainIdBig(chainId)), prv)
+}
+
+// BuildBuiltinTx builds the unsigned tx calling the built-in function, the nonce, gas and
+// gas price are fetched from the node. The fee free functions are sent with zero gas price.
+func (pc *Client) BuildBuiltinTx(ctx context.Context, from common.Address, value *big.Int, data []byte) (*types.Transaction, error) {
+	if len(data) < 4 {
+		return nil, errors.New("invalid built-in function data")
+	}
+	function, err := pabi.FunctionTypeFromId(data[:4])
+	if err != nil {
+		return nil, err
+	}
+
+	nonce, err := pc.PendingNonceAt(ctx, from)
+	if err != nil {
+		return nil, err
+	}
+
+	gasPrice := new(big.Int)
+	if !function.IsFeeFree() {
+		if gasPrice, err = pc.SuggestGasPrice(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	gas, err := pc.EstimateGas(ctx, ethereum.CallMsg{
+		From:     from,
+		To:       &pabi.ChainContractMagicAddr,
+		GasPrice: gasPrice,
+		Value:    value,
+		Data:     data,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return NewBuiltinTx(nonce, value, gas, gasPrice, data), nil
+}
@@ -0,0 +1,207 @@
+package pchainclient
+
+import (
+	"context"
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/tendermint/go-crypto"
+	"github.com/tendermint/go-wire"
+	"math/big"
+	"time"
+)
+
+// ChainStatus is the status of a chain returned by chain_getAllChains
+type ChainStatus struct {
+	ChainID    string            `json:"chain_id"`
+	Owner      common.Address    `json:"owner"`
+	Number     uint64            `json:"current_epoch"`
+	StartTime  time.Time         `json:"epoch_start_time"`
+	Validators []*ChainValidator `json:"validators"`
+}
+
+type ChainValidator struct {
+	Account     common.Address `json:"address"`
+	VotingPower *big.Int       `json:"voting_power"`
+}
+
+// TransferStatus is the status of a cross chain transfer returned by chain_getTransferStatus
+type TransferStatus struct {
+	Id       common.Hash    `json:"id"`
+	Type     string         `json:"type"`
+	From     common.Address `json:"from"`
+	ChainId  string         `json:"chain_id"`
+	Amount   *hexutil.Big   `json:"amount"`
+	Status   string         `json:"status"`
+	FirstTx  common.Hash    `json:"first_tx"`
+	SecondTx common.Hash    `json:"second_tx"`
+	Error    string         `json:"error,omitempty"`
+}
+
+// PendingTX3 is a tx3 not yet withdrawn, returned by chain_listPendingTX3
+type PendingTX3 struct {
+	ChainId     string         `json:"chain_id"`
+	TxHash      common.Hash    `json:"tx_hash"`
+	Amount      *hexutil.Big   `json:"amount"`
+	BlockNumber hexutil.Uint64 `json:"block_number"`
+}
+
+// ChildChainProof is the registration and the epoch of a child chain in the main chain state with their merkle
+// proofs, returned by chain_getChildChainProof
+type ChildChainProof struct {
+	ChainId      string               `json:"chain_id"`
+	BlockNumber  *hexutil.Big         `json:"block_number"`
+	StateRoot    common.Hash          `json:"state_root"`
+	Pending      bool                 `json:"pending"`
+	Registration *ChildChainDataProof `json:"registration"`
+	Epoch        *ChildChainDataProof `json:"epoch"`
+}
+
+type ChildChainDataProof struct {
+	Key   hexutil.Bytes   `json:"key"`
+	Value hexutil.Bytes   `json:"value"`
+	Proof []hexutil.Bytes `json:"proof"`
+}
+
+func (pc *Client) sendTx(ctx context.Context, method string, args ...interface{}) (common.Hash, error) {
+	var hash common.Hash
+	err := pc.c.CallContext(ctx, &hash, method, args...)
+	return hash, err
+}
+
+// CreateChildChain sends the tx to create a child chain, it should be called on the main chain
+func (pc *Client) CreateChildChain(ctx context.Context, from common.Address, chainId string, minValidators uint,
+	minDepositAmount, startBlock, endBlock, gasPrice *big.Int) (common.Hash, error) {
+	return pc.sendTx(ctx, "chain_createChildChain", from, chainId, hexutil.Uint(minValidators),
+		(*hexutil.Big)(minDepositAmount), (*hexutil.Big)(startBlock), (*hexutil.Big)(endBlock), (*hexutil.Big)(gasPrice))
+}
+
+// JoinChildChain sends the tx to join a child chain as validator, it should be called on the main chain
+func (pc *Client) JoinChildChain(ctx context.Context, from common.Address, pubkey crypto.BLSPubKey, chainId string,
+	depositAmount *big.Int, signature []byte, gasPrice *big.Int) (common.Hash, error) {
+	return pc.sendTx(ctx, "chain_joinChildChain", from, pubkey, chainId, (*hexutil.Big)(depositAmount), hexutil.Bytes(signature), (*hexutil.Big)(gasPrice))
+}
+
+// DepositInMainChain sends the TX1 of the deposit, it should be called on the main chain
+func (pc *Client) DepositInMainChain(ctx context.Context, from common.Address, chainId string, amount, gasPrice *big.Int) (common.Hash, error) {
+	return pc.sendTx(ctx, "chain_depositInMainChain", from, chainId, (*hexutil.Big)(amount), (*hexutil.Big)(gasPrice))
+}
+
+// DepositInChildChain sends the TX2 of the deposit, it should be called on the child chain
+func (pc *Client) DepositInChildChain(ctx context.Context, from common.Address, txHash common.Hash) (common.Hash, error) {
+	return pc.sendTx(ctx, "chain_depositInChildChain", from, txHash)
+}
+
+// WithdrawFromChildChain sends the TX3 of the withdraw, it should be called on the child chain
+func (pc *Client) WithdrawFromChildChain(ctx context.Context, from common.Address, amount, gasPrice *big.Int) (common.Hash, error) {
+	return pc.sendTx(ctx, "chain_withdrawFromChildChain", from, (*hexutil.Big)(amount), (*hexutil.Big)(gasPrice))
+}
+
+// WithdrawFromMainChain sends the TX4 of the withdraw, it should be called on the main chain
+func (pc *Client) WithdrawFromMainChain(ctx context.Context, from common.Address, amount *big.Int, chainId string, txHash common.Hash) (common.Hash, error) {
+	return pc.sendTx(ctx, "chain_withdrawFromMainChain", from, (*hexutil.Big)(amount), chainId, txHash)
+}
+
+func (pc *Client) GetTxFromChildChainByHash(ctx context.Context, chainId string, txHash common.Hash) (common.Hash, error) {
+	var hash common.Hash
+	err := pc.c.CallContext(ctx, &hash, "chain_getTxFromChildChainByHash", chainId, txHash)
+	return hash, err
+}
+
+// GetAllTX1 returns the TX1s of the address not yet deposited in the child chain, nil number means the latest block
+func (pc *Client) GetAllTX1(ctx context.Context, from common.Address, number *big.Int) ([]common.Hash, error) {
+	var hashes []common.Hash
+	err := pc.c.CallContext(ctx, &hashes, "chain_getAllTX1", from, toBlockNumArg(number))
+	return hashes, err
+}
+
+// GetAllTX3 returns the TX3s of the address not yet withdrawn in the main chain, nil number means the latest block
+func (pc *Client) GetAllTX3(ctx context.Context, from common.Address, number *big.Int) ([]common.Hash, error) {
+	var hashes []common.Hash
+	err := pc.c.CallContext(ctx, &hashes, "chain_getAllTX3", from, toBlockNumArg(number))
+	return hashes, err
+}
+
+// GetTX3ProofData returns the rlp encoded proof of the tx3 cached in the main chain
+func (pc *Client) GetTX3ProofData(ctx context.Context, chainId string, txHash common.Hash) ([]byte, error) {
+	var bs hexutil.Bytes
+	err := pc.c.CallContext(ctx, &bs, "chain_getTX3ProofData", chainId, txHash)
+	return bs, err
+}
+
+func (pc *Client) ListPendingTX3(ctx context.Context, from common.Address) ([]*PendingTX3, error) {
+	var result []*PendingTX3
+	err := pc.c.CallContext(ctx, &result, "chain_listPendingTX3", from)
+	return result, err
+}
+
+// BroadcastTX3ProofData sends the rlp encoded tx3 proof to the main chain
+func (pc *Client) BroadcastTX3ProofData(ctx context.Context, proofData []byte) error {
+	return pc.c.CallContext(ctx, nil, "chain_broadcastTX3ProofData", hexutil.Bytes(proofData))
+}
+
+// Transfer starts a cross chain transfer driven by the node, it returns the transfer id
+func (pc *Client) Transfer(ctx context.Context, from common.Address, fromChainId, toChainId string, amount, gasPrice *big.Int) (common.Hash, error) {
+	return pc.sendTx(ctx, "chain_transfer", from, fromChainId, toChainId, (*hexutil.Big)(amount), (*hexutil.Big)(gasPrice))
+}
+
+func (pc *Client) GetTransferStatus(ctx context.Context, id common.Hash) (*TransferStatus, error) {
+	var status *TransferStatus
+	err := pc.c.CallContext(ctx, &status, "chain_getTransferStatus", id)
+	return status, err
+}
+
+func (pc *Client) GetAllChains(ctx context.Context) ([]*ChainStatus, error) {
+	var result []*ChainStatus
+	err := pc.c.CallContext(ctx, &result, "chain_getAllChains")
+	return result, err
+}
+
+// GetChildChainProof returns the registration and the epoch of the child chain with their merkle proofs at the
+// given main chain block, nil for the latest block. LightClient.ChildChain verifies them. It returns
+// ethereum.NotFound if the child chain has been neither created nor launched.
+func (pc *Client) GetChildChainProof(ctx context.Context, chainId string, number *big.Int) (*ChildChainProof, error) {
+	var result *ChildChainProof
+	err := pc.c.CallContext(ctx, &result, "chain_getChildChainProof", chainId, toBlockNumArg(number))
+	if err == nil && result == nil {
+		err = ethereum.NotFound
+	}
+	return result, err
+}
+
+// GetChildChain returns the registration of the child chain at the given main chain block, nil for the latest
+// block, without verifying its proof (see LightClient.ChildChain). The registration is nil if the chain has been
+// neither created nor launched, pending if it is waiting for the validators to join.
+func (pc *Client) GetChildChain(ctx context.Context, chainId string, number *big.Int) (cci *core.CoreChainInfo, pending bool, err error) {
+	proof, err := pc.GetChildChainProof(ctx, chainId, number)
+	if err == ethereum.NotFound {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	if proof.Registration == nil {
+		return nil, false, nil
+	}
+	cci = new(core.CoreChainInfo)
+	if err := wire.ReadBinaryBytes(proof.Registration.Value, cci); err != nil {
+		return nil, false, err
+	}
+	return cci, proof.Pending, nil
+}
+
+// SignAddress signs the address with the consensus private key on the node.
+// SignBLSAddress could be used instead to keep the key offline.
+func (pc *Client) SignAddress(ctx context.Context, from common.Address, consensusPrivateKey []byte) (crypto.BLSSignature, error) {
+	var sig crypto.BLSSignature
+	err := pc.c.CallContext(ctx, &sig, "chain_signAddress", from, hexutil.Bytes(consensusPrivateKey))
+	return sig, err
+}
+
+func toBlockNumArg(number *big.Int) string {
+	if number == nil {
+		return "latest"
+	}
+	return hexutil.EncodeBig(number)
+}
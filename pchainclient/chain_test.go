@@ -0,0 +1,73 @@
+package pchainclient
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/tendermint/go-wire"
+)
+
+// TestChainAPI serves the child chain registrations the same as the chain namespace of the node, nil for
+// the child chain not created
+type TestChainAPI struct {
+	pending map[string]*core.CoreChainInfo
+}
+
+func (api *TestChainAPI) GetChildChainProof(ctx context.Context, chainId string, blockNr rpc.BlockNumber) (*ChildChainProof, error) {
+	cci, ok := api.pending[chainId]
+	if !ok {
+		return nil, nil
+	}
+	return &ChildChainProof{
+		ChainId:      chainId,
+		Pending:      true,
+		Registration: &ChildChainDataProof{Value: wire.BinaryBytes(*cci)},
+	}, nil
+}
+
+func newTestChainClient(t *testing.T, api *TestChainAPI) *Client {
+	server := rpc.NewServer()
+	if err := server.RegisterName("chain", api); err != nil {
+		t.Fatalf("failed to register the chain api: %v", err)
+	}
+	c := rpc.DialInProc(server)
+	return &Client{Client: ethclient.NewClient(c), c: c}
+}
+
+// Tests the registration of the child chain is decoded, and the child chain not created is returned as nil.
+func TestGetChildChain(t *testing.T) {
+	cci := &core.CoreChainInfo{
+		Owner:            common.HexToAddress("0x10"),
+		ChainId:          "child_0",
+		MinValidators:    1,
+		MinDepositAmount: big.NewInt(100),
+		StartBlock:       big.NewInt(3),
+		EndBlock:         big.NewInt(10),
+		JoinedValidators: []core.JoinedValidator{{Address: common.HexToAddress("0x20"), DepositAmount: big.NewInt(50)}},
+	}
+	pc := newTestChainClient(t, &TestChainAPI{pending: map[string]*core.CoreChainInfo{"child_0": cci}})
+	defer pc.Close()
+	ctx := context.Background()
+
+	have, pending, err := pc.GetChildChain(ctx, "child_0", nil)
+	if err != nil {
+		t.Fatalf("failed to get the child chain: %v", err)
+	}
+	if have == nil || !pending || have.Owner != cci.Owner || have.EndBlock.Cmp(cci.EndBlock) != 0 ||
+		len(have.JoinedValidators) != 1 || have.JoinedValidators[0].DepositAmount.Cmp(big.NewInt(50)) != 0 {
+		t.Fatalf("child chain mismatch: %+v (pending %v)", have, pending)
+	}
+
+	if _, err := pc.GetChildChainProof(ctx, "child_1", nil); err != ethereum.NotFound {
+		t.Fatalf("proof of the child chain not created: have %v, want %v", err, ethereum.NotFound)
+	}
+	if have, pending, err := pc.GetChildChain(ctx, "child_1", nil); have != nil || pending || err != nil {
+		t.Fatalf("child chain not created: have %+v (pending %v, err %v), want nil", have, pending, err)
+	}
+}
@@ -0,0 +1,99 @@
+// Package pchainclient provides a client for the PChain RPC API, it covers the PChain
+// specific namespaces (chain, tdm, del) on top of the eth namespace of ethclient.
+package pchainclient
+
+import (
+	"context"
+	"errors"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/rpc"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// Client is the client of one chain on a PChain node. The chains of the node are served
+// on the paths "/<chainId>" of the same RPC endpoint.
+type Client struct {
+	*ethclient.Client
+
+	c       *rpc.Client
+	base    *url.URL     // the endpoint without the chain path
+	chainId string       // empty if the endpoint has no chain path
+	hc      *http.Client // shared by the clients of all the chains of the node
+}
+
+// Dial connects to the chain with the url like "http://localhost:6969/pchain". The client of
+// the other chains on the same node could be got by ForChain.
+func Dial(rawurl string) (*Client, error) {
+	return DialContext(context.Background(), rawurl)
+}
+
+func DialContext(ctx context.Context, rawurl string) (*Client, error) {
+	u, err := url.Parse(rawurl)
+	if err != nil {
+		return nil, err
+	}
+
+	base := *u
+	base.Path = ""
+	chainId := strings.Trim(u.Path, "/")
+
+	return dial(ctx, &base, chainId, new(http.Client))
+}
+
+func dial(ctx context.Context, base *url.URL, chainId string, hc *http.Client) (*Client, error) {
+	endpoint := *base
+	if chainId != "" {
+		endpoint.Path = "/" + chainId
+	}
+
+	var c *rpc.Client
+	var err error
+	switch endpoint.Scheme {
+	case "http", "https":
+		// the http client is shared, so the connections to the node are reused by all the chains
+		c, err = rpc.DialHTTPWithClient(endpoint.String(), hc)
+	case "ws", "wss":
+		c, err = rpc.DialWebsocket(ctx, endpoint.String(), "")
+	default:
+		err = errors.New("no known transport for URL scheme \"" + endpoint.Scheme + "\"")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &Client{
+		Client:  ethclient.NewClient(c),
+		c:       c,
+		base:    base,
+		chainId: chainId,
+		hc:      hc,
+	}, nil
+}
+
+// ForChain returns the client of the chain on the same node. The HTTP connections are
+// shared with the current client, a new connection is made for WebSocket.
+func (pc *Client) ForChain(chainId string) (*Client, error) {
+	if chainId == "" || strings.ContainsAny(chainId, "/;") {
+		return nil, errors.New("invalid chain id")
+	}
+	if chainId == pc.chainId {
+		return pc, nil
+	}
+	return dial(context.Background(), pc.base, chainId, pc.hc)
+}
+
+// ChainId returns the id of the chain which the client connects to
+func (pc *Client) ChainId() string {
+	return pc.chainId
+}
+
+// RPC returns the underlying rpc client
+func (pc *Client) RPC() *rpc.Client {
+	return pc.c
+}
+
+func (pc *Client) Close() {
+	pc.c.Close()
+}
@@ -0,0 +1,37 @@
+package pchainclient
+
+import (
+	"context"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"math/big"
+)
+
+// CandidateStatus is the result of del_checkCandidate
+type CandidateStatus struct {
+	Candidate  bool  `json:"candidate"`
+	Commission uint8 `json:"commission"`
+}
+
+func (pc *Client) Delegate(ctx context.Context, from, candidate common.Address, amount, gasPrice *big.Int) (common.Hash, error) {
+	return pc.sendTx(ctx, "del_delegate", from, candidate, (*hexutil.Big)(amount), (*hexutil.Big)(gasPrice))
+}
+
+func (pc *Client) CancelDelegate(ctx context.Context, from, candidate common.Address, amount, gasPrice *big.Int) (common.Hash, error) {
+	return pc.sendTx(ctx, "del_cancelDelegate", from, candidate, (*hexutil.Big)(amount), (*hexutil.Big)(gasPrice))
+}
+
+func (pc *Client) ApplyCandidate(ctx context.Context, from common.Address, securityDeposit *big.Int, commission uint8, gasPrice *big.Int) (common.Hash, error) {
+	return pc.sendTx(ctx, "del_applyCandidate", from, (*hexutil.Big)(securityDeposit), commission, (*hexutil.Big)(gasPrice))
+}
+
+func (pc *Client) CancelCandidate(ctx context.Context, from common.Address, gasPrice *big.Int) (common.Hash, error) {
+	return pc.sendTx(ctx, "del_cancelCandidate", from, (*hexutil.Big)(gasPrice))
+}
+
+// CheckCandidate returns whether the address is a candidate, nil number means the latest block
+func (pc *Client) CheckCandidate(ctx context.Context, address common.Address, number *big.Int) (*CandidateStatus, error) {
+	var status *CandidateStatus
+	err := pc.c.CallContext(ctx, &status, "del_checkCandidate", address, toBlockNumArg(number))
+	return status, err
+}
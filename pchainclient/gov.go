@@ -0,0 +1,38 @@
+package pchainclient
+
+import (
+	"context"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	tdmTypes "github.com/ethereum/go-ethereum/consensus/tendermint/types"
+	"math/big"
+)
+
+// SubmitProposal submits the proposal to change the chain param to the value, the deposit is refunded when
+// the proposal is tallied at the end of the epoch
+func (pc *Client) SubmitProposal(ctx context.Context, from common.Address, param string, value, deposit, gasPrice *big.Int) (common.Hash, error) {
+	return pc.sendTx(ctx, "gov_submitProposal", from, param, (*hexutil.Big)(value), (*hexutil.Big)(deposit), (*hexutil.Big)(gasPrice))
+}
+
+// VoteProposal votes the proposal with the hash of the SubmitProposal tx as the id
+func (pc *Client) VoteProposal(ctx context.Context, from common.Address, id common.Hash, approve bool, gasPrice *big.Int) (common.Hash, error) {
+	return pc.sendTx(ctx, "gov_voteProposal", from, id, approve, (*hexutil.Big)(gasPrice))
+}
+
+func (pc *Client) DepositProposal(ctx context.Context, from common.Address, id common.Hash, amount, gasPrice *big.Int) (common.Hash, error) {
+	return pc.sendTx(ctx, "gov_depositProposal", from, id, (*hexutil.Big)(amount), (*hexutil.Big)(gasPrice))
+}
+
+// GetProposals returns the proposals submitted in the epoch
+func (pc *Client) GetProposals(ctx context.Context, epochNumber uint64) ([]*tdmTypes.ProposalApi, error) {
+	var proposals []*tdmTypes.ProposalApi
+	err := pc.c.CallContext(ctx, &proposals, "tdm_getProposals", epochNumber)
+	return proposals, err
+}
+
+// GetChainParams returns the params of the epoch which could be changed by the proposals
+func (pc *Client) GetChainParams(ctx context.Context, epochNumber uint64) (map[string]*big.Int, error) {
+	var params map[string]*big.Int
+	err := pc.c.CallContext(ctx, &params, "tdm_getChainParams", epochNumber)
+	return params, err
+}
@@ -0,0 +1,252 @@
+package pchainclient
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/consensus/tendermint/epoch"
+	"github.com/ethereum/go-ethereum/consensus/tendermint/light"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/trie"
+	"github.com/tendermint/go-wire"
+	"math/big"
+)
+
+// AccountProof is the merkle proof of an account and its storage returned by eth_getProof
+type AccountProof struct {
+	Address      common.Address  `json:"address"`
+	AccountProof []hexutil.Bytes `json:"accountProof"`
+	Balance      *hexutil.Big    `json:"balance"`
+	CodeHash     common.Hash     `json:"codeHash"`
+	Nonce        hexutil.Uint64  `json:"nonce"`
+	StorageHash  common.Hash     `json:"storageHash"`
+	StorageProof []StorageProof  `json:"storageProof"`
+}
+
+type StorageProof struct {
+	Key   common.Hash     `json:"key"`
+	Value hexutil.Bytes   `json:"value"`
+	Proof []hexutil.Bytes `json:"proof"`
+}
+
+// GetProof returns the merkle proof of the account and the storage keys at the given block, nil for the latest block
+func (pc *Client) GetProof(ctx context.Context, account common.Address, keys []common.Hash, number *big.Int) (*AccountProof, error) {
+	hexKeys := make([]string, len(keys))
+	for i, key := range keys {
+		hexKeys[i] = key.Hex()
+	}
+
+	var result AccountProof
+	err := pc.c.CallContext(ctx, &result, "eth_getProof", account, hexKeys, toBlockNumArg(number))
+	return &result, err
+}
+
+// LightClient follows a chain by verifying the BLS commits of the headers instead of executing the blocks.
+// Only the first header of each epoch is downloaded to follow the validator set, the other headers and the
+// state proofs are fetched and verified on demand. The state is read from the state root of the header
+// served by the node, which is only trusted from the signed header fork on, see the package light.
+type LightClient struct {
+	pc       *Client
+	verifier *light.Verifier
+}
+
+// NewLightClient creates the light client of the chain which the client connects to, trusting the header
+// with the given hash. The header must carry an epoch, like block 1 or the first block of an epoch. The config
+// must be the one the chain runs with, it tells from which block on the header is signed by the validators.
+func NewLightClient(ctx context.Context, pc *Client, config *params.ChainConfig, trusted common.Hash) (*LightClient, error) {
+	header, err := pc.HeaderByHash(ctx, trusted)
+	if err != nil {
+		return nil, err
+	}
+	if header.Hash() != trusted {
+		return nil, fmt.Errorf("header hash mismatch, want %x, got %x", trusted, header.Hash())
+	}
+
+	verifier, err := light.NewVerifierFromHeader(config, header)
+	if err != nil {
+		return nil, err
+	}
+	return &LightClient{pc: pc, verifier: verifier}, nil
+}
+
+// Verifier returns the verifier which keeps the verified epochs
+func (lc *LightClient) Verifier() *light.Verifier {
+	return lc.verifier
+}
+
+// Sync follows the epochs up to the head of the chain and returns the latest verified epoch
+func (lc *LightClient) Sync(ctx context.Context) (*epoch.Epoch, error) {
+	head, err := lc.pc.BlockNumber(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	for next := lc.verifier.NextEpochStart(); next <= head.Uint64(); next = lc.verifier.NextEpochStart() {
+		header, err := lc.pc.HeaderByNumber(ctx, new(big.Int).SetUint64(next))
+		if err != nil {
+			return nil, err
+		}
+		if _, err := lc.verifier.VerifyNextEpoch(header); err != nil {
+			return nil, fmt.Errorf("verify epoch at block %v failed: %v", next, err)
+		}
+	}
+	return lc.verifier.Latest(), nil
+}
+
+// HeaderByNumber returns the verified header at the given block, nil for the latest block
+func (lc *LightClient) HeaderByNumber(ctx context.Context, number *big.Int) (*types.Header, error) {
+	header, err := lc.pc.HeaderByNumber(ctx, number)
+	if err != nil {
+		return nil, err
+	}
+
+	if header.Number.Uint64() >= lc.verifier.NextEpochStart() {
+		if _, err := lc.Sync(ctx); err != nil {
+			return nil, err
+		}
+	}
+	if _, err := lc.verifier.VerifyHeader(header); err != nil {
+		return nil, err
+	}
+	return header, nil
+}
+
+// stateHeader returns the verified header at the given block, whose state root is signed by the validators
+func (lc *LightClient) stateHeader(ctx context.Context, number *big.Int) (*types.Header, error) {
+	header, err := lc.HeaderByNumber(ctx, number)
+	if err != nil {
+		return nil, err
+	}
+	if !lc.verifier.Config().IsSignedHeader(header.Number) {
+		return nil, light.ErrUnsignedState
+	}
+	return header, nil
+}
+
+// Account returns the account and the values of the storage keys at the given block, verified against the
+// state root of the verified header. The account is nil if it does not exist.
+func (lc *LightClient) Account(ctx context.Context, address common.Address, keys []common.Hash, number *big.Int) (*state.Account, []common.Hash, error) {
+	header, err := lc.stateHeader(ctx, number)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	proof, err := lc.pc.GetProof(ctx, address, keys, header.Number)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	enc, err := verifyProof(header.Root, crypto.Keccak256(address.Bytes()), proof.AccountProof)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid account proof: %v", err)
+	}
+	if enc == nil {
+		return nil, make([]common.Hash, len(keys)), nil
+	}
+
+	var account state.Account
+	if err := rlp.DecodeBytes(enc, &account); err != nil {
+		return nil, nil, err
+	}
+
+	if len(proof.StorageProof) != len(keys) {
+		return nil, nil, errors.New("storage proof count mismatch")
+	}
+	values := make([]common.Hash, len(keys))
+	for i, key := range keys {
+		sp := proof.StorageProof[i]
+		if sp.Key != key {
+			return nil, nil, fmt.Errorf("storage proof key mismatch, want %x, got %x", key, sp.Key)
+		}
+
+		enc, err := verifyProof(account.Root, crypto.Keccak256(key.Bytes()), sp.Proof)
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid storage proof of key %x: %v", key, err)
+		}
+		if enc != nil {
+			_, content, _, err := rlp.Split(enc)
+			if err != nil {
+				return nil, nil, err
+			}
+			values[i].SetBytes(content)
+		}
+		if !bytes.Equal(values[i][:], common.BytesToHash(sp.Value).Bytes()) {
+			return nil, nil, fmt.Errorf("storage value of key %x does not match the proof", key)
+		}
+	}
+	return &account, values, nil
+}
+
+// ChildChain returns the registration and the epoch of the child chain at the given main chain block, verified
+// against the state root of the verified header. The registration is pending if the child chain is not launched
+// yet, the epoch is nil if the child chain has not sent it to the main chain.
+func (lc *LightClient) ChildChain(ctx context.Context, chainId string, number *big.Int) (*core.CoreChainInfo, *epoch.Epoch, error) {
+	header, err := lc.stateHeader(ctx, number)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	proof, err := lc.pc.GetChildChainProof(ctx, chainId, header.Number)
+	if err != nil {
+		return nil, nil, err
+	}
+	if proof.StateRoot != header.Root || proof.Registration == nil {
+		return nil, nil, errors.New("child chain proof does not match the header")
+	}
+
+	regKey := state.ChildChainDataKey(core.ChainInfoKey(chainId, proof.Pending))
+	if err := verifyChildChainData(header.Root, regKey, proof.Registration); err != nil {
+		return nil, nil, fmt.Errorf("invalid registration proof: %v", err)
+	}
+	var cci core.CoreChainInfo
+	if err := wire.ReadBinaryBytes(proof.Registration.Value, &cci); err != nil {
+		return nil, nil, err
+	}
+
+	if proof.Epoch == nil {
+		return &cci, nil, nil
+	}
+	epochKey := state.ChildChainDataKey(core.ChainEpochKey(chainId, cci.EpochNumber))
+	if err := verifyChildChainData(header.Root, epochKey, proof.Epoch); err != nil {
+		return nil, nil, fmt.Errorf("invalid epoch proof: %v", err)
+	}
+	ep := epoch.FromBytes(proof.Epoch.Value)
+	if ep == nil {
+		return nil, nil, errors.New("invalid epoch of the child chain")
+	}
+	return &cci, ep, nil
+}
+
+// verifyChildChainData checks the child chain data is the value of the key proved against the state root
+func verifyChildChainData(root common.Hash, key []byte, data *ChildChainDataProof) error {
+	if !bytes.Equal(data.Key, key) {
+		return fmt.Errorf("key mismatch, want %q, got %q", key, []byte(data.Key))
+	}
+	value, err := verifyProof(root, crypto.Keccak256(key), data.Proof)
+	if err != nil {
+		return err
+	}
+	if len(value) == 0 || !bytes.Equal(value, data.Value) {
+		return errors.New("value does not match the proof")
+	}
+	return nil
+}
+
+// verifyProof returns the value of the key proved by the trie nodes, nil if the key does not exist
+func verifyProof(root common.Hash, key []byte, nodes []hexutil.Bytes) ([]byte, error) {
+	db, _ := ethdb.NewMemDatabase()
+	for _, node := range nodes {
+		db.Put(crypto.Keccak256(node), node)
+	}
+	value, err, _ := trie.VerifyProof(root, key, db)
+	return value, err
+}
@@ -0,0 +1,90 @@
+package pchainclient
+
+import (
+	"context"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/tendermint/go-crypto"
+	"math/big"
+	"time"
+)
+
+// EpochValidator is the validator of tdm_getEpoch, tdm_getNextEpochVote and tdm_getNextEpochValidators,
+// the public key is the BLS key of the consensus
+type EpochValidator struct {
+	Address common.Address   `json:"address"`
+	PubKey  crypto.BLSPubKey `json:"public_key"`
+	Amount  *big.Int         `json:"voting_power"`
+}
+
+// Epoch is the result of tdm_getEpoch
+type Epoch struct {
+	Number           uint64            `json:"number"`
+	RewardPerBlock   *big.Int          `json:"reward_per_block"`
+	StartBlock       uint64            `json:"start_block"`
+	EndBlock         uint64            `json:"end_block"`
+	StartTime        time.Time         `json:"start_time"`
+	EndTime          time.Time         `json:"end_time"`
+	VoteStartBlock   uint64            `json:"vote_start_block"`
+	VoteEndBlock     uint64            `json:"vote_end_block"`
+	RevealStartBlock uint64            `json:"reveal_start_block"`
+	RevealEndBlock   uint64            `json:"reveal_end_block"`
+	Status           int               `json:"status"`
+	Validators       []*EpochValidator `json:"validators"`
+}
+
+// EpochVote is the revealed vote for the validator election of next epoch
+type EpochVote struct {
+	EpochValidator
+	Salt     string      `json:"salt"`
+	VoteHash common.Hash `json:"vote_hash"`
+	TxHash   common.Hash `json:"tx_hash"`
+}
+
+// EpochVotes is the result of tdm_getNextEpochVote
+type EpochVotes struct {
+	EpochNumber uint64       `json:"vote_for_epoch"`
+	StartBlock  uint64       `json:"start_block"`
+	EndBlock    uint64       `json:"end_block"`
+	Votes       []*EpochVote `json:"votes"`
+}
+
+// VoteNextEpoch sends the hash of the vote for the validator election of next epoch
+func (pc *Client) VoteNextEpoch(ctx context.Context, from common.Address, voteHash common.Hash, gasPrice *big.Int) (common.Hash, error) {
+	return pc.sendTx(ctx, "tdm_voteNextEpoch", from, voteHash, (*hexutil.Big)(gasPrice))
+}
+
+// RevealVote reveals the vote sent by VoteNextEpoch
+func (pc *Client) RevealVote(ctx context.Context, from common.Address, pubkey crypto.BLSPubKey, amount *big.Int, salt string,
+	signature []byte, gasPrice *big.Int) (common.Hash, error) {
+	return pc.sendTx(ctx, "tdm_revealVote", from, pubkey, (*hexutil.Big)(amount), salt, hexutil.Bytes(signature), (*hexutil.Big)(gasPrice))
+}
+
+// GetCurrentEpochNumber returns the number of the current epoch
+func (pc *Client) GetCurrentEpochNumber(ctx context.Context) (uint64, error) {
+	var number uint64
+	err := pc.c.CallContext(ctx, &number, "tdm_getCurrentEpochNumber")
+	return number, err
+}
+
+// GetEpoch returns the epoch with the number, it should not be greater than the current one
+func (pc *Client) GetEpoch(ctx context.Context, number uint64) (*Epoch, error) {
+	var ep *Epoch
+	err := pc.c.CallContext(ctx, &ep, "tdm_getEpoch", number)
+	return ep, err
+}
+
+// GetNextEpochVote returns the votes for the validator election of next epoch
+func (pc *Client) GetNextEpochVote(ctx context.Context) (*EpochVotes, error) {
+	var votes *EpochVotes
+	err := pc.c.CallContext(ctx, &votes, "tdm_getNextEpochVote")
+	return votes, err
+}
+
+// GetNextEpochValidators returns the validators of next epoch elected by the revealed votes so far, it fails
+// before the reveal stage
+func (pc *Client) GetNextEpochValidators(ctx context.Context) ([]*EpochValidator, error) {
+	var validators []*EpochValidator
+	err := pc.c.CallContext(ctx, &validators, "tdm_getNextEpochValidators")
+	return validators, err
+}
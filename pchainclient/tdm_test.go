@@ -0,0 +1,100 @@
+package pchainclient
+
+import (
+	"context"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	tdmTypes "github.com/ethereum/go-ethereum/consensus/tendermint/types"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/tendermint/go-crypto"
+)
+
+// TestTdmAPI serves the epochs the same as the tdm namespace of the node
+type TestTdmAPI struct {
+	validator *tdmTypes.EpochValidator
+}
+
+func (api *TestTdmAPI) GetCurrentEpochNumber() (uint64, error) {
+	return 3, nil
+}
+
+func (api *TestTdmAPI) GetEpoch(number uint64) (*tdmTypes.EpochApi, error) {
+	return &tdmTypes.EpochApi{
+		Number:         number,
+		RewardPerBlock: big.NewInt(10),
+		StartBlock:     100,
+		EndBlock:       199,
+		StartTime:      time.Unix(1000, 0).UTC(),
+		Validators:     []*tdmTypes.EpochValidator{api.validator},
+	}, nil
+}
+
+func (api *TestTdmAPI) GetNextEpochVote() (*tdmTypes.EpochVotesApi, error) {
+	return &tdmTypes.EpochVotesApi{
+		EpochNumber: 4,
+		Votes: []*tdmTypes.EpochValidatorVoteApi{{
+			EpochValidator: *api.validator,
+			Salt:           "salt",
+			VoteHash:       common.HexToHash("0x1"),
+		}},
+	}, nil
+}
+
+func (api *TestTdmAPI) GetNextEpochValidators() ([]*tdmTypes.EpochValidator, error) {
+	return []*tdmTypes.EpochValidator{api.validator}, nil
+}
+
+func newTestTdmClient(t *testing.T, api *TestTdmAPI) *Client {
+	server := rpc.NewServer()
+	if err := server.RegisterName("tdm", api); err != nil {
+		t.Fatalf("failed to register the tdm api: %v", err)
+	}
+	c := rpc.DialInProc(server)
+	return &Client{Client: ethclient.NewClient(c), c: c}
+}
+
+// Tests the epochs returned by the node are decoded with the BLS public keys of the validators.
+func TestGetEpoch(t *testing.T) {
+	var pubkey crypto.BLSPubKey
+	pubkey[0], pubkey[127] = 1, 2
+	validator := &tdmTypes.EpochValidator{Address: common.HexToAddress("0x10"), PubKey: pubkey, Amount: big.NewInt(1000)}
+	pc := newTestTdmClient(t, &TestTdmAPI{validator: validator})
+	defer pc.Close()
+	ctx := context.Background()
+
+	if number, err := pc.GetCurrentEpochNumber(ctx); err != nil || number != 3 {
+		t.Fatalf("current epoch number mismatch: have %d (%v), want 3", number, err)
+	}
+
+	ep, err := pc.GetEpoch(ctx, 2)
+	if err != nil {
+		t.Fatalf("failed to get the epoch: %v", err)
+	}
+	if ep.Number != 2 || ep.RewardPerBlock.Cmp(big.NewInt(10)) != 0 || ep.EndBlock != 199 || !ep.StartTime.Equal(time.Unix(1000, 0)) {
+		t.Fatalf("epoch mismatch: %+v", ep)
+	}
+	if len(ep.Validators) != 1 || ep.Validators[0].PubKey != pubkey || ep.Validators[0].Address != validator.Address ||
+		ep.Validators[0].Amount.Cmp(validator.Amount) != 0 {
+		t.Fatalf("epoch validators mismatch: %+v", ep.Validators)
+	}
+
+	votes, err := pc.GetNextEpochVote(ctx)
+	if err != nil {
+		t.Fatalf("failed to get the votes: %v", err)
+	}
+	if votes.EpochNumber != 4 || len(votes.Votes) != 1 || votes.Votes[0].PubKey != pubkey || votes.Votes[0].Salt != "salt" {
+		t.Fatalf("votes mismatch: %+v", votes)
+	}
+
+	validators, err := pc.GetNextEpochValidators(ctx)
+	if err != nil {
+		t.Fatalf("failed to get the next validators: %v", err)
+	}
+	if len(validators) != 1 || validators[0].PubKey != pubkey {
+		t.Fatalf("next validators mismatch: %+v", validators)
+	}
+}
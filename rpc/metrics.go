@@ -0,0 +1,125 @@
+package rpc
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/ethereum/go-ethereum/metrics"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+const metricsPath = "/metrics"
+
+var metricsQuantiles = []float64{0.5, 0.75, 0.95, 0.99}
+
+// metricsHandler exports the metrics in the Prometheus text format. The metrics of each chain
+// are labelled with the chain id, the metrics shared by all the chains of the node have no label.
+func metricsHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		families := make(map[string]*metricFamily)
+
+		metrics.DefaultRegistry.Each(func(name string, i interface{}) {
+			addMetric(families, name, "", i)
+		})
+		metrics.EachChainRegistry(func(chainId string, registry metrics.Registry) {
+			registry.Each(func(name string, i interface{}) {
+				addMetric(families, name, chainId, i)
+			})
+		})
+
+		names := make([]string, 0, len(families))
+		for name := range families {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		var buf bytes.Buffer
+		for _, name := range names {
+			f := families[name]
+			fmt.Fprintf(&buf, "# TYPE %s %s\n", name, f.typ)
+			for _, s := range f.samples {
+				buf.WriteString(s)
+			}
+		}
+
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+		w.Write(buf.Bytes())
+	})
+}
+
+// metricFamily is the samples of the metrics with the same name from all the chains
+type metricFamily struct {
+	typ     string
+	samples []string
+}
+
+func addMetric(families map[string]*metricFamily, name, chainId string, i interface{}) {
+
+	name = promName(name)
+	add := func(typ string, lines ...string) {
+		f, ok := families[name]
+		if !ok {
+			f = &metricFamily{typ: typ}
+			families[name] = f
+		} else if f.typ != typ {
+			// the same name is used by metrics of different types, keep the first one
+			return
+		}
+		f.samples = append(f.samples, lines...)
+	}
+
+	switch m := i.(type) {
+	case metrics.Counter:
+		add("counter", sample(name, chainId, "", m.Count()))
+	case metrics.Gauge:
+		add("gauge", sample(name, chainId, "", m.Value()))
+	case metrics.GaugeFloat64:
+		add("gauge", sample(name, chainId, "", m.Value()))
+	case metrics.Meter:
+		add("counter", sample(name, chainId, "", m.Snapshot().Count()))
+	case metrics.Histogram:
+		h := m.Snapshot()
+		add("summary", summary(name, chainId, h.Percentiles(metricsQuantiles), float64(h.Sum()), h.Count(), 1)...)
+	case metrics.Timer:
+		// timers are recorded in nanoseconds, exported in seconds
+		t := m.Snapshot()
+		add("summary", summary(name, chainId, t.Percentiles(metricsQuantiles), float64(t.Sum()), t.Count(), 1e9)...)
+	}
+}
+
+func summary(name, chainId string, ps []float64, sum float64, count int64, unit float64) []string {
+	lines := make([]string, 0, len(ps)+2)
+	for i, q := range metricsQuantiles {
+		lines = append(lines, sample(name, chainId, fmt.Sprintf("quantile=\"%v\"", q), ps[i]/unit))
+	}
+	lines = append(lines, sample(name+"_sum", chainId, "", sum/unit))
+	lines = append(lines, sample(name+"_count", chainId, "", count))
+	return lines
+}
+
+func sample(name, chainId, label string, value interface{}) string {
+	var labels []string
+	if chainId != "" {
+		labels = append(labels, fmt.Sprintf("chain=%q", chainId))
+	}
+	if label != "" {
+		labels = append(labels, label)
+	}
+
+	if len(labels) == 0 {
+		return fmt.Sprintf("%s %v\n", name, value)
+	}
+	return fmt.Sprintf("%s{%s} %v\n", name, strings.Join(labels, ","), value)
+}
+
+// promName converts the metric name like "consensus/round/duration" to "pchain_consensus_round_duration"
+func promName(name string) string {
+	return "pchain_" + strings.Map(func(r rune) rune {
+		if (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') || r == '_' {
+			return r
+		}
+		return '_'
+	}, name)
+}
@@ -0,0 +1,40 @@
+package rpc
+
+import (
+	"io/ioutil"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/metrics"
+)
+
+// Tests the metrics of each chain are exported with the chain label, and the shared ones without.
+func TestMetricsHandler(t *testing.T) {
+	metrics.Enabled = true
+	defer func() { metrics.Enabled = false }()
+
+	metrics.NewRegisteredCounter("test/shared", metrics.DefaultRegistry).Inc(3)
+	metrics.NewRegisteredCounter("test/height", metrics.ChainRegistry("child_0")).Inc(7)
+	metrics.NewRegisteredCounter("test/height", metrics.ChainRegistry("pchain")).Inc(9)
+	metrics.NewRegisteredTimer("test/round", metrics.ChainRegistry("pchain")).Update(2 * time.Second)
+
+	rec := httptest.NewRecorder()
+	metricsHandler().ServeHTTP(rec, httptest.NewRequest("GET", metricsPath, nil))
+	body, _ := ioutil.ReadAll(rec.Body)
+	out := string(body)
+
+	for _, line := range []string{
+		"# TYPE pchain_test_shared counter\npchain_test_shared 3\n",
+		"# TYPE pchain_test_height counter\npchain_test_height{chain=\"child_0\"} 7\npchain_test_height{chain=\"pchain\"} 9\n",
+		"# TYPE pchain_test_round summary\n",
+		"pchain_test_round{chain=\"pchain\",quantile=\"0.5\"} 2\n",
+		"pchain_test_round_sum{chain=\"pchain\"} 2\n",
+		"pchain_test_round_count{chain=\"pchain\"} 1\n",
+	} {
+		if !strings.Contains(out, line) {
+			t.Errorf("missing %q in the output:\n%s", line, out)
+		}
+	}
+}
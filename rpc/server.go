@@ -1,19 +1,27 @@
 package rpc
 
 import (
+	"encoding/json"
 	"fmt"
 	"github.com/ethereum/go-ethereum/cmd/utils"
 	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/metrics"
 	"github.com/tendermint/go-rpc/server"
 	"gopkg.in/urfave/cli.v1"
 	"net"
 	"net/http"
+	"sort"
 	"strconv"
+	"strings"
+	"sync"
 )
 
 var listeners map[string]net.Listener
 var muxes map[string]*http.ServeMux
 
+var chainsMtx sync.Mutex
+var chains = make(map[string]bool) // chains with rpc handler hooked up
+
 func Hookup(chainId string, handler http.Handler) error {
 
 	log.Infof("Hookup RPC for (chainId, rpc Handler): (%v, %v)", chainId, handler)
@@ -25,6 +33,12 @@ func Hookup(chainId string, handler http.Handler) error {
 		}
 	}
 
+	if handler != nil {
+		chainsMtx.Lock()
+		chains[chainId] = true
+		chainsMtx.Unlock()
+	}
+
 	return nil
 }
 
@@ -42,6 +56,10 @@ func StartRPC(ctx *cli.Context) error {
 	for _, addr := range addrArr {
 
 		mux := http.NewServeMux()
+		mux.Handle("/", defaultHandler())
+		if metrics.Enabled {
+			mux.Handle(metricsPath, metricsHandler())
+		}
 		listener, err := rpcserver.StartHTTPServer(addr, mux)
 		if err != nil {
 			return err
@@ -62,10 +80,28 @@ func StopRPC() {
 	}
 }
 
+// defaultHandler serves the paths without chain, it lists the chains available on the node
 func defaultHandler() http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 
-		w.WriteHeader(http.StatusNotImplemented)
-		w.Write([]byte(fmt.Sprintf("blank output for not existed chain\n")))
+		chainsMtx.Lock()
+		available := make([]string, 0, len(chains))
+		for chainId := range chains {
+			available = append(available, chainId)
+		}
+		chainsMtx.Unlock()
+		sort.Strings(available)
+
+		resp := struct {
+			Error  string   `json:"error,omitempty"`
+			Chains []string `json:"chains"`
+		}{Chains: available}
+
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Path != "/" {
+			resp.Error = fmt.Sprintf("chain %s is not available on this node", strings.TrimPrefix(r.URL.Path, "/"))
+			w.WriteHeader(http.StatusNotFound)
+		}
+		json.NewEncoder(w).Encode(resp)
 	})
 }
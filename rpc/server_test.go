@@ -0,0 +1,44 @@
+package rpc
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+// Tests the default handler lists the chains hooked up, and reports the unknown chain.
+func TestDefaultHandler(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.Handle("/", defaultHandler())
+	muxes = map[string]*http.ServeMux{"test": mux}
+	Hookup("pchain", http.NotFoundHandler())
+	Hookup("child_0", http.NotFoundHandler())
+	Hookup("child_1", nil)
+
+	var resp struct {
+		Error  string   `json:"error"`
+		Chains []string `json:"chains"`
+	}
+	for _, tt := range []struct {
+		path  string
+		code  int
+		error string
+	}{
+		{"/", http.StatusOK, ""},
+		{"/child_1", http.StatusNotFound, "chain child_1 is not available on this node"},
+	} {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest("GET", tt.path, nil))
+		if rec.Code != tt.code {
+			t.Errorf("%s: status have %d, want %d", tt.path, rec.Code, tt.code)
+		}
+		if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+			t.Fatalf("%s: failed to decode the response: %v", tt.path, err)
+		}
+		if resp.Error != tt.error || !reflect.DeepEqual(resp.Chains, []string{"child_0", "pchain"}) {
+			t.Errorf("%s: have %q %v, want %q [child_0 pchain]", tt.path, resp.Error, resp.Chains, tt.error)
+		}
+	}
+}